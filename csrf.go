@@ -1,10 +1,14 @@
 package inertia
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/labstack/gommon/random"
 )
 
 // CSRF is a middleware for protecting cross-site request forgery with Inertia.js
@@ -21,8 +25,14 @@ var DefaultCSRFConfig = CSRFConfig{
 	CookieMaxAge:   86400,
 	CookieSameSite: http.SameSiteDefaultMode,
 	CookiePath:     "/",
+	ErrorHandler:   CSRFErrorHandler,
 }
 
+// PageExpiredMessage is the flash message that is sent when the CSRF token has expired.
+var PageExpiredMessage = "The page expired, please try again."
+
+const csrfConfigKey = "__inertia_csrf_config__"
+
 func CSRF() echo.MiddlewareFunc {
 	return CSRFWithConfig(DefaultCSRFConfig)
 }
@@ -52,6 +62,76 @@ func CSRFWithConfig(config CSRFConfig) echo.MiddlewareFunc {
 	if config.CookiePath == "" {
 		config.CookiePath = DefaultCSRFConfig.CookiePath
 	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = DefaultCSRFConfig.ErrorHandler
+	}
+
+	csrf := middleware.CSRFWithConfig(middleware.CSRFConfig(config))
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		h := csrf(next)
+		return func(c echo.Context) error {
+			// The config is used to regenerate the token.
+			c.Set(csrfConfigKey, &config)
+			return h(c)
+		}
+	}
+}
+
+// CSRFErrorHandler handles a CSRF token mismatch as "page expired".
+// For an Inertia request, it redirects back with the "message" flash message, so that the user is not stuck on an error modal.
+// For other requests, it returns an HTTP error with the 419 status code.
+// A missing token is handled as "page expired" as well as an invalid one, like Laravel does,
+// because the token is missing when the cookie has expired. Echo returns 400 for it by default.
+// The original error of Echo is set to the internal error.
+// see https://inertiajs.com/csrf-protection#handling-mismatches
+func CSRFErrorHandler(err error, c echo.Context) error {
+	if c.Request().Header.Get(HeaderXInertia) != "" {
+		if i, ierr := Get(c); ierr == nil {
+			i.Flash("message", PageExpiredMessage)
+			return i.Back()
+		}
+	}
+	return echo.NewHTTPError(StatusPageExpired, PageExpiredMessage).SetInternal(fmt.Errorf("csrf: %w", err))
+}
+
+// RegenerateCSRFToken generates a new CSRF token and sends it to the client.
+// You should call it after login and logout to prevent session fixation attacks.
+func RegenerateCSRFToken(c echo.Context) (string, error) {
+	config, ok := c.Get(csrfConfigKey).(*CSRFConfig)
+	if !ok {
+		return "", ErrNoCSRFConfig
+	}
+
+	token := random.String(config.TokenLength, random.Alphabetic)
+
+	cookie := new(http.Cookie)
+	cookie.Name = config.CookieName
+	cookie.Value = token
+	if config.CookiePath != "" {
+		cookie.Path = config.CookiePath
+	}
+	if config.CookieDomain != "" {
+		cookie.Domain = config.CookieDomain
+	}
+	if config.CookieSameSite != http.SameSiteDefaultMode {
+		cookie.SameSite = config.CookieSameSite
+	}
+	cookie.Expires = time.Now().Add(time.Duration(config.CookieMaxAge) * time.Second)
+	cookie.Secure = config.CookieSecure
+	cookie.HttpOnly = config.CookieHTTPOnly
+
+	// Replace the cookie that has been set by the CSRF middleware.
+	header := c.Response().Header()
+	cookies := header.Values(echo.HeaderSetCookie)
+	header.Del(echo.HeaderSetCookie)
+	for _, v := range cookies {
+		if !strings.HasPrefix(v, config.CookieName+"=") {
+			header.Add(echo.HeaderSetCookie, v)
+		}
+	}
+	c.SetCookie(cookie)
 
-	return middleware.CSRFWithConfig(middleware.CSRFConfig(config))
+	c.Set(config.ContextKey, token)
+	return token, nil
 }
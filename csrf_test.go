@@ -48,3 +48,114 @@ func TestCSRFWithConfig(t *testing.T) {
 		}
 	})
 }
+
+func TestCSRFErrorHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		inertia bool
+		// token is the token in the header. The cookie has the token "token".
+		token string
+	}{
+		{
+			name:    "inertia request with an invalid token",
+			inertia: true,
+			token:   "invalid",
+		},
+		{
+			// Echo returns 400 for a missing token, but it is handled as "page expired" as well.
+			name:    "inertia request without a token",
+			inertia: true,
+		},
+		{
+			name:  "non inertia request with an invalid token",
+			token: "invalid",
+		},
+		{
+			name: "non inertia request without a token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryFlashStore()
+			e := echo.New()
+			e.HTTPErrorHandler = HTTPErrorHandler()
+			e.Use(MiddlewareWithConfig(MiddlewareConfig{
+				Renderer:    testNewMockRenderer(t, func(ctx *RenderContext) error { return nil }),
+				VersionFunc: func() string { return "" },
+				FlashStore:  store,
+			}))
+			e.Use(CSRF())
+			e.POST("/users", func(c echo.Context) error {
+				t.Error("expected the handler not to be called")
+				return nil
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/users", nil)
+			if tt.inertia {
+				req.Header.Set(HeaderXInertia, "true")
+			}
+			req.Header.Set("Referer", "/users/create")
+			req.AddCookie(&http.Cookie{Name: "XSRF-TOKEN", Value: "token"})
+			if tt.token != "" {
+				req.Header.Set("X-XSRF-TOKEN", tt.token)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if !tt.inertia {
+				if rec.Code != StatusPageExpired {
+					t.Errorf("expected status 419, got %d", rec.Code)
+				}
+				return
+			}
+			if rec.Code != http.StatusFound {
+				t.Errorf("expected status 302, got %d", rec.Code)
+			}
+			if loc := rec.Header().Get(echo.HeaderLocation); loc != "/users/create" {
+				t.Errorf("expected redirect to /users/create, got %q", loc)
+			}
+			flash, _ := store.Data()[flashKeyMessages].(map[string]any)
+			if flash["message"] != PageExpiredMessage {
+				t.Errorf("expected the page expired message, got %v", store.Data())
+			}
+		})
+	}
+}
+
+func TestRegenerateCSRFToken(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "XSRF-TOKEN", Value: "old-token"})
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	var token string
+	h := CSRF()(func(c echo.Context) error {
+		var err error
+		token, err = RegenerateCSRFToken(c)
+		if err != nil {
+			return err
+		}
+		if c.Get("csrf") != token {
+			t.Errorf("expected the context to have the new token, got %v", c.Get("csrf"))
+		}
+		return c.String(http.StatusOK, "test")
+	})
+	if err := h(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if token == "" || token == "old-token" {
+		t.Fatalf("expected a new token, got %q", token)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != token {
+		t.Errorf("expected only the new token cookie, got %v", cookies)
+	}
+
+	_, err := RegenerateCSRFToken(e.NewContext(req, httptest.NewRecorder()))
+	if err != ErrNoCSRFConfig {
+		t.Errorf("expected ErrNoCSRFConfig, got %v", err)
+	}
+}
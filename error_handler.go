@@ -0,0 +1,170 @@
+package inertia
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// StatusPageExpired is the status code that is used when the CSRF token has expired.
+// It is not a standard HTTP status code, but it is widely used by Laravel and Inertia.js applications.
+const StatusPageExpired = 419
+
+type HTTPErrorHandlerConfig struct {
+	Skipper middleware.Skipper
+	// Component is the page component that renders errors.
+	Component string
+	// Statuses is a list of the status codes that are rendered with the component.
+	// The other errors are handled by the Fallback handler.
+	Statuses []int
+	// Components maps status codes to page components.
+	// It takes precedence over Component and Statuses.
+	Components map[int]string
+	// Fallback handles the errors that are not rendered with a component.
+	// If it is nil, Echo's default error handler is used.
+	Fallback echo.HTTPErrorHandler
+}
+
+var DefaultHTTPErrorHandlerConfig = HTTPErrorHandlerConfig{
+	Skipper:    middleware.DefaultSkipper,
+	Component:  "Error",
+	Statuses:   []int{http.StatusForbidden, http.StatusNotFound, StatusPageExpired, http.StatusInternalServerError, http.StatusServiceUnavailable},
+	Components: nil,
+	Fallback:   nil,
+}
+
+// HTTPErrorHandler returns an echo.HTTPErrorHandler that renders errors with the "Error" page component,
+// instead of the raw error responses that Inertia.js displays in a modal.
+// see https://inertiajs.com/error-handling
+//
+// The component receives the "status" and "message" props.
+// In debug mode, it also receives the "detail" prop, which is the error formatted with "%+v",
+// and the "stack" prop if the error has the stack trace of a recovered panic, such as a PropError.
+//
+//	e.HTTPErrorHandler = inertia.HTTPErrorHandler()
+func HTTPErrorHandler() echo.HTTPErrorHandler {
+	return HTTPErrorHandlerWithConfig(DefaultHTTPErrorHandlerConfig)
+}
+
+func HTTPErrorHandlerWithConfig(config HTTPErrorHandlerConfig) echo.HTTPErrorHandler {
+	if config.Skipper == nil {
+		config.Skipper = DefaultHTTPErrorHandlerConfig.Skipper
+	}
+	if config.Component == "" {
+		config.Component = DefaultHTTPErrorHandlerConfig.Component
+	}
+	if config.Statuses == nil {
+		config.Statuses = DefaultHTTPErrorHandlerConfig.Statuses
+	}
+
+	fallback := func(err error, c echo.Context) {
+		if config.Fallback != nil {
+			config.Fallback(err, c)
+			return
+		}
+		c.Echo().DefaultHTTPErrorHandler(err, c)
+	}
+
+	return func(err error, c echo.Context) {
+		if c.Response().Committed || config.Skipper(c) {
+			fallback(err, c)
+			return
+		}
+
+		i, ierr := Get(c)
+		if ierr != nil {
+			// The Inertia middleware has not been applied.
+			fallback(err, c)
+			return
+		}
+
+		status, message := resolveHTTPError(err, c.Echo().Debug)
+		component := config.component(status)
+		if component == "" {
+			fallback(err, c)
+			return
+		}
+
+		if c.Request().Method == http.MethodHead {
+			if rerr := c.NoContent(status); rerr != nil {
+				c.Logger().Error(rerr)
+			}
+			return
+		}
+
+		props := map[string]any{
+			"status":  status,
+			"message": message,
+		}
+		if c.Echo().Debug {
+			props["detail"] = fmt.Sprintf("%+v", err)
+			if stack := errorStack(err); stack != nil {
+				props["stack"] = string(stack)
+			}
+		}
+
+		if rerr := i.render(status, component, props, nil); rerr != nil {
+			c.Logger().Error(rerr)
+			fallback(err, c)
+		}
+	}
+}
+
+func (config HTTPErrorHandlerConfig) component(status int) string {
+	if component, ok := config.Components[status]; ok {
+		return component
+	}
+	for _, s := range config.Statuses {
+		if s == status {
+			return config.Component
+		}
+	}
+	return ""
+}
+
+// resolveHTTPError returns the status code and the message of the error.
+// The message of an internal error is hidden unless debug mode is enabled.
+func resolveHTTPError(err error, debug bool) (int, string) {
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		// The internal HTTP error is used only if it is set directly, like Echo's default error handler does.
+		// The internal error that wraps an HTTP error, such as the 400 or 403 error of Echo's CSRF middleware
+		// that CSRFErrorHandler wraps, is only the cause, so the outer status code is kept.
+		if internal, ok := he.Internal.(*echo.HTTPError); ok {
+			he = internal
+		}
+		if msg, ok := he.Message.(string); ok && msg != "" {
+			return he.Code, msg
+		}
+		return he.Code, statusText(he.Code)
+	}
+
+	if debug {
+		return http.StatusInternalServerError, err.Error()
+	}
+	return http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+}
+
+// errorStack returns the stack trace of the panic that the error is recovered from.
+// It returns nil if the error does not have a stack trace.
+func errorStack(err error) []byte {
+	var pe *PropError
+	if errors.As(err, &pe) && pe.Stack != nil {
+		return pe.Stack
+	}
+	var panicErr *panicError
+	if errors.As(err, &panicErr) {
+		return panicErr.stack
+	}
+	return nil
+}
+
+func statusText(code int) string {
+	if code == StatusPageExpired {
+		return "Page Expired"
+	}
+	return http.StatusText(code)
+}
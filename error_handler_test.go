@@ -0,0 +1,182 @@
+package inertia
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	tests := []struct {
+		name              string
+		err               error
+		config            HTTPErrorHandlerConfig
+		debug             bool
+		expectedStatus    int
+		expectedComponent string
+		expectedMessage   string
+		expectedStack     bool
+	}{
+		{
+			name:              "not found",
+			err:               echo.ErrNotFound,
+			config:            DefaultHTTPErrorHandlerConfig,
+			expectedStatus:    http.StatusNotFound,
+			expectedComponent: "Error",
+			expectedMessage:   "Not Found",
+		},
+		{
+			name:              "internal error hides the message",
+			err:               errors.New("database is down"),
+			config:            DefaultHTTPErrorHandlerConfig,
+			expectedStatus:    http.StatusInternalServerError,
+			expectedComponent: "Error",
+			expectedMessage:   "Internal Server Error",
+		},
+		{
+			name:              "internal error in debug mode",
+			err:               errors.New("database is down"),
+			config:            DefaultHTTPErrorHandlerConfig,
+			debug:             true,
+			expectedStatus:    http.StatusInternalServerError,
+			expectedComponent: "Error",
+			expectedMessage:   "database is down",
+		},
+		{
+			name: "panic in a prop in debug mode",
+			err: (&propEvaluator{component: "Home"}).evaluateProps(map[string]any{
+				"stats": func() any { panic("boom") },
+			}),
+			config:            DefaultHTTPErrorHandlerConfig,
+			debug:             true,
+			expectedStatus:    http.StatusInternalServerError,
+			expectedComponent: "Error",
+			expectedMessage:   `inertia-echo: failed to evaluate the callback prop "stats" of the component "Home": panic: boom`,
+			expectedStack:     true,
+		},
+		{
+			name:              "page expired",
+			err:               echo.NewHTTPError(StatusPageExpired),
+			config:            DefaultHTTPErrorHandlerConfig,
+			expectedStatus:    StatusPageExpired,
+			expectedComponent: "Error",
+			expectedMessage:   "Page Expired",
+		},
+		{
+			name:              "internal http error",
+			err:               echo.NewHTTPError(http.StatusInternalServerError).SetInternal(echo.ErrForbidden),
+			config:            DefaultHTTPErrorHandlerConfig,
+			expectedStatus:    http.StatusForbidden,
+			expectedComponent: "Error",
+			expectedMessage:   "Forbidden",
+		},
+		{
+			// The wrapped error is only the cause, so the status code of CSRFErrorHandler is kept.
+			name:              "wrapped internal http error",
+			err:               echo.NewHTTPError(StatusPageExpired, PageExpiredMessage).SetInternal(fmt.Errorf("csrf: %w", echo.ErrForbidden)),
+			config:            DefaultHTTPErrorHandlerConfig,
+			expectedStatus:    StatusPageExpired,
+			expectedComponent: "Error",
+			expectedMessage:   PageExpiredMessage,
+		},
+		{
+			name: "per-status component",
+			err:  echo.ErrForbidden,
+			config: HTTPErrorHandlerConfig{
+				Components: map[int]string{http.StatusForbidden: "Errors/Forbidden"},
+			},
+			expectedStatus:    http.StatusForbidden,
+			expectedComponent: "Errors/Forbidden",
+			expectedMessage:   "Forbidden",
+		},
+		{
+			name:           "status that is not rendered with a component",
+			err:            echo.ErrBadRequest,
+			config:         DefaultHTTPErrorHandlerConfig,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Debug = tt.debug
+			e.HTTPErrorHandler = HTTPErrorHandlerWithConfig(tt.config)
+			e.Use(MiddlewareWithConfig(MiddlewareConfig{
+				Renderer:    testNewMockRenderer(t, func(ctx *RenderContext) error { return nil }),
+				VersionFunc: func() string { return "" },
+				FlashStore:  NewMemoryFlashStore(),
+			}))
+			e.GET("/", func(c echo.Context) error {
+				return tt.err
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(HeaderXInertia, "true")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedComponent == "" {
+				if rec.Header().Get(HeaderXInertia) != "" {
+					t.Error("expected the error not to be rendered with a component")
+				}
+				return
+			}
+
+			var page Page
+			if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+				t.Fatal(err)
+			}
+			if page.Component != tt.expectedComponent {
+				t.Errorf("expected component %q, got %q", tt.expectedComponent, page.Component)
+			}
+			if page.Props["status"] != float64(tt.expectedStatus) {
+				t.Errorf("expected status prop %d, got %v", tt.expectedStatus, page.Props["status"])
+			}
+			if page.Props["message"] != tt.expectedMessage {
+				t.Errorf("expected message prop %q, got %v", tt.expectedMessage, page.Props["message"])
+			}
+			if _, ok := page.Props["detail"]; ok != tt.debug {
+				t.Errorf("expected detail prop only in debug mode, got %v", page.Props["detail"])
+			}
+			if _, ok := page.Props["stack"]; ok != tt.expectedStack {
+				t.Errorf("expected stack prop only for a recovered panic in debug mode, got %v", page.Props["stack"])
+			}
+		})
+	}
+}
+
+func TestHTTPErrorHandler_FirstLoad(t *testing.T) {
+	rendered := false
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Renderer: testNewMockRenderer(t, func(ctx *RenderContext) error {
+			rendered = true
+			if ctx.Page.Component != "Error" {
+				t.Errorf("expected component Error, got %q", ctx.Page.Component)
+			}
+			return nil
+		}),
+		FlashStore: NewMemoryFlashStore(),
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if !rendered {
+		t.Error("expected the error to be rendered by the renderer")
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
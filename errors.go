@@ -2,9 +2,52 @@ package inertia
 
 import (
 	"errors"
+	"fmt"
 )
 
 var (
 	ErrNoInertiaContext      = errors.New("inertia-echo: echo.Context does not have 'Inertia'")
 	ErrRendererNotRegistered = errors.New("inertia-echo: renderer not registered")
+	ErrNoFlashSecret         = errors.New("inertia-echo: a secret is required to sign the flash cookie, set MiddlewareConfig.FlashSecret or the INERTIA_FLASH_SECRET environment variable")
+	ErrNoCSRFConfig          = errors.New("inertia-echo: echo.Context does not have the CSRF config")
+	ErrFlashCookieTooLarge   = errors.New("inertia-echo: the flash cookie is too large")
 )
+
+// PropError is the error that occurs while evaluating a prop.
+// A panic in the callback of a prop is recovered into a PropError as well.
+type PropError struct {
+	// Path is the key path of the prop, like "user.permissions".
+	// The indexes of slices are included in the path, like "widgets.0".
+	Path string
+	// Kind is the kind of the prop: "defer", "optional", "lazy", "always", "merge", "scroll", "once", "fallback" or "callback".
+	Kind string
+	// Component is the page component that is being rendered.
+	Component string
+	// Err is the error that the prop returns.
+	Err error
+	// Stack is the stack trace of the panic. It is nil if the prop does not panic.
+	Stack []byte
+}
+
+func (e *PropError) Error() string {
+	return fmt.Sprintf("inertia-echo: failed to evaluate the %s prop %q of the component %q: %v", e.Kind, e.Path, e.Component, e.Err)
+}
+
+func (e *PropError) Unwrap() error {
+	return e.Err
+}
+
+// panicError is the error that is recovered from a panic.
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+func (e *panicError) Unwrap() error {
+	err, _ := e.value.(error)
+	return err
+}
@@ -45,6 +45,8 @@ func main() {
 	e.Use(session.Middleware(session.NewCookieStore([]byte("secret"))))
 	e.Use(inertia.MiddlewareWithConfig(inertia.MiddlewareConfig{
 		Renderer: r,
+		// Use your own secret, like an environment variable, in production.
+		FlashSecret: []byte("example-secret"),
 	}))
 	e.Use(inertia.CSRF())
 	e.Use(inertia.EncryptHistoryMiddleware())
@@ -90,11 +92,10 @@ func main() {
 		}
 
 		if form.Email != "kohki.makimoto@gmail.com" {
-			// display the login page again if the email is not correct
-			return inertia.Render(c, "Login", map[string]any{
-				"errors": map[string]string{
-					"email": "Invalid email address",
-				},
+			// redirect back to the login page with the errors if the email is not correct
+			inertia.WithInput(c, form)
+			return inertia.BackWithErrors(c, inertia.ValidationErrors{
+				"email": "Invalid email address",
 			})
 		}
 
@@ -107,6 +108,11 @@ func main() {
 		}
 		c.Logger().Debugf("User authenticated: %s", form.Email)
 
+		// Rotate the CSRF token after login
+		if _, err := inertia.RegenerateCSRFToken(c); err != nil {
+			return err
+		}
+
 		// Redirect to the home page after login
 		inertia.ClearHistory(c)
 		return c.Redirect(http.StatusFound, "/")
@@ -121,6 +127,11 @@ func main() {
 		}
 		c.Logger().Debug("User logged out")
 
+		// Rotate the CSRF token after logout
+		if _, err := inertia.RegenerateCSRFToken(c); err != nil {
+			return err
+		}
+
 		// Redirect to the login page after logout
 		inertia.ClearHistory(c)
 		return c.Redirect(http.StatusFound, "/login")
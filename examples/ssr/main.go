@@ -43,6 +43,8 @@ func main() {
 
 	e.Use(inertia.MiddlewareWithConfig(inertia.MiddlewareConfig{
 		Renderer: r,
+		// Use your own secret, like an environment variable, in production.
+		FlashSecret: []byte("example-secret"),
 	}))
 	e.Use(inertia.CSRF())
 
@@ -51,6 +51,8 @@ func main() {
 	// setup inertia
 	e.Use(inertia.MiddlewareWithConfig(inertia.MiddlewareConfig{
 		Renderer: r,
+		// Use your own secret, like an environment variable, in production.
+		FlashSecret: []byte("example-secret"),
 	}))
 	e.Use(inertia.CSRF())
 
@@ -0,0 +1,319 @@
+package inertia
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// FlashStore stores data that survives until the next request.
+// The adapter uses it to keep its state, such as the clear history flag, validation errors, flash messages and old input,
+// across the redirect-then-render cycle.
+//
+// Note:
+// The official inertia-laravel adapter uses a session for this purpose,
+// but the Echo framework lacks a built-in session store.
+// You can implement this interface with your own session store.
+type FlashStore interface {
+	// Get returns the data that was stored by a previous request.
+	Get(c echo.Context) (map[string]any, error)
+	// Set stores the data for the next request.
+	// If the data is empty, the stored data must be cleared.
+	Set(c echo.Context, data map[string]any) error
+}
+
+const (
+	flashKeyClearHistory = "clearHistory"
+	flashKeyErrors       = "errors"
+	flashKeyMessages     = "flash"
+	flashKeyOldInput     = "old"
+)
+
+// CookieFlashStore is a FlashStore that stores the data in a signed cookie.
+// The data is encoded as JSON, so the values are restored as JSON types (e.g. map[string]any, float64) on the next request.
+// The cookie is signed but not encrypted, so the data, such as the old input and the validation errors, is readable on the client.
+// Note that the size of a cookie is limited to about 4KB by browsers.
+// Set returns ErrFlashCookieTooLarge instead of sending a cookie that exceeds 4096 bytes, which browsers drop silently.
+type CookieFlashStore struct {
+	// Name is the name of the cookie.
+	Name string
+	// Secret is a key to sign the cookie.
+	Secret []byte
+	// Path is the path of the cookie.
+	Path string
+	// Domain is the domain of the cookie.
+	Domain string
+	// Secure is a flag that determines whether the cookie is only sent over HTTPS.
+	Secure bool
+	// SameSite is the SameSite attribute of the cookie.
+	SameSite http.SameSite
+}
+
+// NewCookieFlashStore creates a CookieFlashStore.
+// It panics if the secret is empty.
+// The secret must be the same among all the instances of your application.
+func NewCookieFlashStore(secret []byte) *CookieFlashStore {
+	if len(secret) == 0 {
+		panic(ErrNoFlashSecret)
+	}
+
+	return &CookieFlashStore{
+		Name:     "inertia.flash",
+		Secret:   secret,
+		Path:     "/",
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// maxFlashCookieSize is the maximum size of the flash cookie in bytes, which browsers accept.
+const maxFlashCookieSize = 4096
+
+var errInvalidFlashCookie = errors.New("inertia-echo: invalid flash cookie")
+
+func (s *CookieFlashStore) Get(c echo.Context) (map[string]any, error) {
+	cookie, err := c.Cookie(s.Name)
+	if err != nil {
+		if errors.Is(err, http.ErrNoCookie) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	data, err := s.decode(cookie.Value)
+	if err != nil {
+		// A tampered or an outdated cookie is simply ignored.
+		return nil, nil
+	}
+	return data, nil
+}
+
+func (s *CookieFlashStore) Set(c echo.Context, data map[string]any) error {
+	if len(data) == 0 {
+		if _, err := c.Cookie(s.Name); err != nil {
+			// There is nothing to clear.
+			return nil
+		}
+		c.SetCookie(s.newCookie("", -1))
+		return nil
+	}
+
+	value, err := s.encode(data)
+	if err != nil {
+		return err
+	}
+	cookie := s.newCookie(value, 0)
+	if size := len(cookie.String()); size > maxFlashCookieSize {
+		// The stale data of the previous request must not be shown again.
+		if _, err := c.Cookie(s.Name); err == nil {
+			c.SetCookie(s.newCookie("", -1))
+		}
+		return fmt.Errorf("%w: %d bytes exceeds %d bytes", ErrFlashCookieTooLarge, size, maxFlashCookieSize)
+	}
+	c.SetCookie(cookie)
+	return nil
+}
+
+func (s *CookieFlashStore) newCookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     s.Name,
+		Value:    value,
+		Path:     s.Path,
+		Domain:   s.Domain,
+		Secure:   s.Secure,
+		SameSite: s.SameSite,
+		HttpOnly: true,
+		MaxAge:   maxAge,
+	}
+}
+
+func (s *CookieFlashStore) encode(data map[string]any) (string, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.sign(payload)), nil
+}
+
+func (s *CookieFlashStore) decode(value string) (map[string]any, error) {
+	payload, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, errInvalidFlashCookie
+	}
+	b, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(b, s.sign(payload)) {
+		return nil, errInvalidFlashCookie
+	}
+	b, err = base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errInvalidFlashCookie
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, errInvalidFlashCookie
+	}
+	return data, nil
+}
+
+func (s *CookieFlashStore) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// MemoryFlashStore is a FlashStore that stores the data in memory.
+// It is intended for tests. The data is shared among all the clients.
+type MemoryFlashStore struct {
+	mutex sync.Mutex
+	data  map[string]any
+}
+
+func NewMemoryFlashStore() *MemoryFlashStore {
+	return &MemoryFlashStore{}
+}
+
+func (s *MemoryFlashStore) Get(c echo.Context) (map[string]any, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.copy(), nil
+}
+
+func (s *MemoryFlashStore) Set(c echo.Context, data map[string]any) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(data) == 0 {
+		s.data = nil
+		return nil
+	}
+	s.data = make(map[string]any, len(data))
+	for k, v := range data {
+		s.data[k] = v
+	}
+	return nil
+}
+
+// Data returns a copy of the stored data.
+func (s *MemoryFlashStore) Data() map[string]any {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.copy()
+}
+
+func (s *MemoryFlashStore) copy() map[string]any {
+	if s.data == nil {
+		return nil
+	}
+	data := make(map[string]any, len(s.data))
+	for k, v := range s.data {
+		data[k] = v
+	}
+	return data
+}
+
+// Flash stores a flash message.
+// The flash messages are sent to the client as the "flash" prop on the next render,
+// which may be the current request or the next request after a redirect.
+func (i *Inertia) Flash(key string, value any) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.flashMessages == nil {
+		i.flashMessages = map[string]any{}
+	}
+	i.flashMessages[key] = value
+}
+
+// loadFlash restores the state that was stored by a previous request.
+func (i *Inertia) loadFlash() error {
+	data, err := i.flashStore.Get(i.echoContext)
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	i.hasFlashed = len(data) > 0
+	i.mu.Unlock()
+
+	if v, ok := data[flashKeyClearHistory].(bool); ok && v {
+		i.ClearHistory()
+	}
+	if v := toValidationErrors(data[flashKeyErrors]); len(v) > 0 {
+		i.WithErrors(v)
+	}
+	if v, ok := data[flashKeyMessages].(map[string]any); ok {
+		for k, vv := range v {
+			i.Flash(k, vv)
+		}
+	}
+	if v, ok := data[flashKeyOldInput].(map[string]any); ok {
+		i.WithInput(v)
+	}
+	return nil
+}
+
+// saveFlash stores the state that has not been sent to the client yet, so that the next request can use it.
+// It is called just before the response is written.
+func (i *Inertia) saveFlash() {
+	i.mu.RLock()
+	data := map[string]any{}
+	if i.clearHistory {
+		data[flashKeyClearHistory] = true
+	}
+	if len(i.validationErrors) > 0 {
+		errs := ValidationErrors{}
+		errs.Merge(i.validationErrors)
+		data[flashKeyErrors] = errs
+	}
+	if len(i.flashMessages) > 0 {
+		data[flashKeyMessages] = i.copyProps(i.flashMessages)
+	}
+	if len(i.oldInput) > 0 {
+		data[flashKeyOldInput] = i.copyProps(i.oldInput)
+	}
+	hasFlashed := i.hasFlashed
+	i.mu.RUnlock()
+
+	if len(data) == 0 && !hasFlashed {
+		return
+	}
+	if err := i.flashStore.Set(i.echoContext, data); err != nil {
+		i.echoContext.Logger().Errorf("inertia-echo: failed to store flash data: %v", err)
+	}
+}
+
+// flushFlash resets the state after it has been sent to the client.
+func (i *Inertia) flushFlash() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.clearHistory = false
+	i.validationErrors = nil
+	i.flashMessages = nil
+	i.oldInput = nil
+}
+
+func toValidationErrors(v any) ValidationErrors {
+	switch vv := v.(type) {
+	case ValidationErrors:
+		return vv
+	case map[string]string:
+		return vv
+	case map[string]any:
+		return NewValidationErrors(vv)
+	default:
+		return nil
+	}
+}
+
+func Flash(c echo.Context, key string, value any) {
+	MustGet(c).Flash(key, value)
+}
@@ -0,0 +1,248 @@
+package inertia
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestCookieFlashStore(t *testing.T) {
+	store := NewCookieFlashStore([]byte("secret"))
+	e := echo.New()
+
+	// store the data
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if err := store.Set(c, map[string]any{"key": "value"}); err != nil {
+		t.Fatal(err)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "inertia.flash" {
+		t.Fatalf("expected the flash cookie, got %v", cookies)
+	}
+
+	// restore the data
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	data, err := store.Get(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data["key"] != "value" {
+		t.Errorf("expected the stored data, got %v", data)
+	}
+
+	// clear the data
+	if err := store.Set(c, nil); err != nil {
+		t.Fatal(err)
+	}
+	cleared := rec.Result().Cookies()
+	if len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Errorf("expected the flash cookie to be deleted, got %v", cleared)
+	}
+
+	// a tampered cookie is ignored
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "inertia.flash", Value: cookies[0].Value + "x"})
+	c = e.NewContext(req, httptest.NewRecorder())
+	data, err = store.Get(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data != nil {
+		t.Errorf("expected the tampered cookie to be ignored, got %v", data)
+	}
+}
+
+func TestCookieFlashStore_TooLarge(t *testing.T) {
+	store := NewCookieFlashStore([]byte("secret"))
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "inertia.flash", Value: "previous"})
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	err := store.Set(c, map[string]any{"old": map[string]any{"body": strings.Repeat("a", 4096)}})
+	if !errors.Is(err, ErrFlashCookieTooLarge) {
+		t.Fatalf("expected ErrFlashCookieTooLarge, got %v", err)
+	}
+	// The cookie of the previous request is deleted, so that its data is not shown again.
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("expected the flash cookie to be deleted, got %v", cookies)
+	}
+}
+
+func TestFlashStore_RedirectThenRender(t *testing.T) {
+	store := NewMemoryFlashStore()
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Renderer:    testNewMockRenderer(t, func(ctx *RenderContext) error { return nil }),
+		VersionFunc: func() string { return "" },
+		FlashStore:  store,
+	}))
+	e.POST("/users", func(c echo.Context) error {
+		ClearHistory(c)
+		WithErrors(c, ValidationErrors{"name": "The name field is required."})
+		Flash(c, "message", "Please fix the errors.")
+		return c.Redirect(http.StatusFound, "/users/create")
+	})
+	e.GET("/users/create", func(c echo.Context) error {
+		return Render(c, "Users/Create", nil)
+	})
+
+	// redirect stores the state
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	req.Header.Set(HeaderXInertia, "true")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected status 302, got %d", rec.Code)
+	}
+	if len(store.Data()) != 3 {
+		t.Fatalf("expected the state to be stored, got %v", store.Data())
+	}
+
+	// render consumes the state
+	req = httptest.NewRequest(http.MethodGet, "/users/create", nil)
+	req.Header.Set(HeaderXInertia, "true")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var page Page
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if !page.ClearHistory {
+		t.Error("expected clearHistory to be true")
+	}
+	if !testDeepEqual(t, page.Props["errors"], map[string]any{"name": "The name field is required."}) {
+		t.Errorf("unexpected errors: %v", page.Props["errors"])
+	}
+	if !testDeepEqual(t, page.Props["flash"], map[string]any{"message": "Please fix the errors."}) {
+		t.Errorf("unexpected flash: %v", page.Props["flash"])
+	}
+	if store.Data() != nil {
+		t.Errorf("expected the state to be cleared, got %v", store.Data())
+	}
+
+	// the next render does not have the state anymore
+	req = httptest.NewRequest(http.MethodGet, "/users/create", nil)
+	req.Header.Set(HeaderXInertia, "true")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	page = Page{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if page.ClearHistory {
+		t.Error("expected clearHistory to be false")
+	}
+	if !testDeepEqual(t, page.Props["errors"], map[string]any{}) {
+		t.Errorf("expected empty errors, got: %v", page.Props["errors"])
+	}
+	if _, ok := page.Props["flash"]; ok {
+		t.Errorf("expected no flash, got: %v", page.Props["flash"])
+	}
+}
+
+func TestFlashStore_NonInertiaRedirect(t *testing.T) {
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Renderer:   testNewMockRenderer(t, func(ctx *RenderContext) error { return nil }),
+		FlashStore: NewCookieFlashStore([]byte("secret")),
+	}))
+	e.GET("/logout", func(c echo.Context) error {
+		ClearHistory(c)
+		return c.Redirect(http.StatusFound, "/login")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/logout", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "inertia.flash" {
+		t.Errorf("expected the flash cookie to be sent with the redirect, got %v", cookies)
+	}
+}
+
+func TestFlashStore_RenderError(t *testing.T) {
+	store := NewMemoryFlashStore()
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Renderer: testNewMockRenderer(t, func(ctx *RenderContext) error {
+			return errors.New("render error")
+		}),
+		VersionFunc: func() string { return "" },
+		FlashStore:  store,
+	}))
+	e.GET("/", func(c echo.Context) error {
+		Flash(c, "message", "User created.")
+		return Render(c, "Home", nil)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", rec.Code)
+	}
+	// The state is kept for the next render, because the page has not been sent.
+	if !testDeepEqual(t, store.Data()[flashKeyMessages], map[string]any{"message": "User created."}) {
+		t.Errorf("expected the flash messages to be kept, got %v", store.Data())
+	}
+}
+
+func TestMiddlewareWithConfig_FlashSecret(t *testing.T) {
+	// The cookies signed by different middleware instances with the same secret are valid.
+	for _, tt := range []struct {
+		name   string
+		config MiddlewareConfig
+		env    string
+	}{
+		{name: "config", config: MiddlewareConfig{FlashSecret: []byte("secret")}},
+		{name: "environment variable", env: "secret"},
+		// A random key is shared in the process, and a warning is logged.
+		{name: "no secret"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INERTIA_FLASH_SECRET", tt.env)
+
+			var flash any
+			e := echo.New()
+			g1 := e.Group("", MiddlewareWithConfig(tt.config))
+			g1.POST("/users", func(c echo.Context) error {
+				Flash(c, "message", "User created.")
+				return c.Redirect(http.StatusFound, "/users")
+			})
+			g2 := e.Group("", MiddlewareWithConfig(tt.config))
+			g2.GET("/users", func(c echo.Context) error {
+				flash = MustGet(c).flashMessages["message"]
+				return nil
+			})
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", nil))
+
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			for _, c := range rec.Result().Cookies() {
+				req.AddCookie(c)
+			}
+			e.ServeHTTP(httptest.NewRecorder(), req)
+			if flash != "User created." {
+				t.Errorf("expected the flash message to be restored, got %v", flash)
+			}
+		})
+	}
+}
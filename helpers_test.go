@@ -58,6 +58,20 @@ func testDeepEqual(t *testing.T, a, b any) bool {
 			}
 		}
 		return true
+	case []any:
+		vb, ok := b.([]any)
+		if !ok {
+			return false
+		}
+		if len(va) != len(vb) {
+			return false
+		}
+		for i, v := range va {
+			if !testDeepEqual(t, v, vb[i]) {
+				return false
+			}
+		}
+		return true
 	case []int:
 		vb, ok := b.([]int)
 		if !ok {
@@ -2,14 +2,13 @@ package inertia
 
 import (
 	"bytes"
-	"errors"
-	"fmt"
+	"encoding/json"
 	"io"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/labstack/echo/v4"
-	"github.com/mitchellh/mapstructure"
 )
 
 const (
@@ -21,24 +20,53 @@ const (
 	HeaderXInertiaPartialData      = "X-Inertia-Partial-Data"
 	HeaderXInertiaPartialExcept    = "X-Inertia-Partial-Except"
 	HeaderXInertiaReset            = "X-Inertia-Reset"
+
+	HeaderXInertiaInfiniteScrollMergeIntent = "X-Inertia-Infinite-Scroll-Merge-Intent"
+	HeaderXInertiaExceptOnceProps           = "X-Inertia-Except-Once-Props"
 )
 
 // Inertia is a echo.Context wrapper that handles Inertia.js protocol.
+//
+// The methods are safe for concurrent use, so they can be called from the callbacks of the props
+// that are evaluated concurrently (see MiddlewareConfig.MaxPropConcurrency).
 type Inertia struct {
-	echoContext           echo.Context
-	rootView              string
-	sharedProps           map[string]any
-	sharedPropsMutex      sync.RWMutex
-	version               VersionFunc
-	renderer              Renderer
-	encryptHistory        bool
-	clearHistoryCookieKey string
-	clearHistory          bool
-	isSsrDisabled         bool
-	partialComponent      string
-	onlyProps             []string
-	exceptProps           []string
-	resetProps            []string
+	// mu guards the state that can be changed by the methods, such as the shared props and the flags.
+	mu                   sync.RWMutex
+	echoContext          echo.Context
+	rootView             string
+	rootViewSet          bool
+	rootViewFunc         RootViewFunc
+	rootViewFuncOnce     sync.Once
+	sharedProps          map[string]any
+	shareFunc            SharedDataFunc
+	sharedData           lazyData
+	sharedViewData       map[string]any
+	shareViewFunc        SharedDataFunc
+	shareViewFuncData    lazyData
+	version              VersionFunc
+	renderer             Renderer
+	encryptHistory       bool
+	clearHistory         bool
+	isSsrDisabled        bool
+	partialComponent     string
+	onlyProps            []string
+	exceptProps          []string
+	resetProps           []string
+	exceptOnceProps      []string
+	isPrefetch           bool
+	mergeIntent          string
+	errorBag             string
+	validationErrors     ValidationErrors
+	flashStore           FlashStore
+	flashMessages        map[string]any
+	hasFlashed           bool
+	oldInput             map[string]any
+	backFallbackURL      string
+	dontFlash            []string
+	maxPropConcurrency   int
+	onPropError          PropErrorFunc
+	propsDecodeMode      PropsDecodeMode
+	sharedPropsMergeMode SharedPropsMergeMode
 }
 
 func (i *Inertia) EchoContext() echo.Context {
@@ -46,100 +74,116 @@ func (i *Inertia) EchoContext() echo.Context {
 }
 
 func (i *Inertia) SetRenderer(r Renderer) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
 	i.renderer = r
 }
 
 func (i *Inertia) Renderer() Renderer {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
 	return i.renderer
 }
 
 func (i *Inertia) EncryptHistory(encrypt bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
 	i.encryptHistory = encrypt
 }
 
 // ClearHistory clears the history.
 // see https://inertiajs.com/history-encryption
 func (i *Inertia) ClearHistory() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
 	i.clearHistory = true
 }
 
-// pullClearHistory pulls the clear history flag from the cookie or the current state.
-// Note:
-// The design of the inertia-echo package used a dedicated cookie to store the clear history flag.
-// While the official inertia-laravel adapter uses a session for this purpose,
-// the Echo framework lacks a built-in session store, so we use a cookie as an alternative.
-func (i *Inertia) pullClearHistory() bool {
-	// Reset clearHistory after reading the current state or cookie value.
-	defer func() {
-		i.clearHistory = false
-	}()
-
-	// Check if the clear history cookie is set
-	cookie, err := i.echoContext.Request().Cookie(i.clearHistoryCookieKey)
-	if err != nil {
-		if errors.Is(err, http.ErrNoCookie) {
-			// No cookie found, use the current state
-			return i.clearHistory
-		}
-	}
-
-	// You got the cookie value, therefore you should delete the cookie
-	http.SetCookie(i.echoContext.Response(), &http.Cookie{
-		Name:     i.clearHistoryCookieKey,
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		MaxAge:   -1, // Negative value tells browser to delete immediately
-	})
-
-	if cookie.Value == "true" {
-		return true
-	}
-
-	return false
-}
-
-func (i *Inertia) sendClearHistoryCookieIfNeeded() {
-	if i.clearHistory {
-		// In this case, you called the ClearHistory method, but you still haven't called the pullClearHistory method.
-		// Typically, this happens when you call ClearHistory and then redirect to another page.
-		// To keep the clearHistory flag, you need to set the cookie.
-		http.SetCookie(i.echoContext.Response(), &http.Cookie{
-			Name:     i.clearHistoryCookieKey,
-			Value:    "true",
-			Path:     "/",
-			HttpOnly: true,
-		})
-	}
-}
-
 func (i *Inertia) IsSsrDisabled() bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
 	return i.isSsrDisabled
 }
 
 func (i *Inertia) IsSsrEnabled() bool {
-	return !i.isSsrDisabled
+	return !i.IsSsrDisabled()
 }
 
 func (i *Inertia) EnableSsr() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
 	i.isSsrDisabled = false
 }
 
 func (i *Inertia) DisableSsr() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
 	i.isSsrDisabled = true
 }
 
+// SetMaxPropConcurrency sets the maximum number of the props that are evaluated concurrently in this request.
+// It overrides MiddlewareConfig.MaxPropConcurrency. If n is less than or equal to 1, the props are evaluated sequentially.
+func (i *Inertia) SetMaxPropConcurrency(n int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.maxPropConcurrency = n
+}
+
+func (i *Inertia) MaxPropConcurrency() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.maxPropConcurrency
+}
+
+// IsPrefetch reports whether the request is a prefetch request.
+// see https://inertiajs.com/prefetching
+func (i *Inertia) IsPrefetch() bool {
+	return i.isPrefetch
+}
+
+type RootViewFunc func(c echo.Context) string
+
+// SetRootView sets the root template of this request.
+// It takes precedence over MiddlewareConfig.RootViewFunc.
 func (i *Inertia) SetRootView(name string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
 	i.rootView = name
+	i.rootViewSet = true
 }
 
+// RootView returns the root template of this request.
+// If SetRootView has not been called, it is resolved by MiddlewareConfig.RootViewFunc,
+// and MiddlewareConfig.RootView is used when the function returns an empty string.
+// The function is called at most once per request, and the result is reused.
 func (i *Inertia) RootView() string {
+	i.rootViewFuncOnce.Do(func() {
+		i.mu.RLock()
+		set, f := i.rootViewSet, i.rootViewFunc
+		i.mu.RUnlock()
+		if set || f == nil {
+			return
+		}
+
+		// The function is called without the lock, because it may call the methods of the Inertia.
+		v := f(i.echoContext)
+
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		// SetRootView that is called while the function is running takes precedence.
+		if !i.rootViewSet && v != "" {
+			i.rootView = v
+		}
+	})
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
 	return i.rootView
 }
 
 func (i *Inertia) Share(props map[string]any) {
-	i.sharedPropsMutex.Lock()
-	defer i.sharedPropsMutex.Unlock()
+	i.mu.Lock()
+	defer i.mu.Unlock()
 
 	// merge shared props
 	for k, v := range props {
@@ -147,28 +191,60 @@ func (i *Inertia) Share(props map[string]any) {
 	}
 }
 
+// Shared returns the shared props, including the props of MiddlewareConfig.Share.
+// The props shared by Share take precedence over the props of MiddlewareConfig.Share.
+// The returned map is a snapshot, and its nested maps (map[string]any) are copied as well,
+// so changing it does not affect the shared props. The other values, such as slices, are not copied.
+// Since MiddlewareConfig.Share is called lazily, Shared may call it. If it returns an error,
+// the error is logged, and only the props shared by Share are returned. Render returns the error instead.
 func (i *Inertia) Shared() map[string]any {
-	i.sharedPropsMutex.RLock()
-	defer i.sharedPropsMutex.RUnlock()
+	sharedData, err := i.resolveSharedData()
+	if err != nil {
+		i.echoContext.Logger().Error(err)
+	}
 
-	return i.sharedProps
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return copyNestedMaps(i.mergeProps(sharedData, i.sharedProps))
 }
 
+// FlushShared removes the shared props, including the props of MiddlewareConfig.Share.
 func (i *Inertia) FlushShared() {
-	i.sharedPropsMutex.Lock()
-	defer i.sharedPropsMutex.Unlock()
+	// The lock is not held while resetting, because resetting waits for MiddlewareConfig.Share, which may call Share.
+	i.sharedData.reset()
 
+	i.mu.Lock()
+	defer i.mu.Unlock()
 	i.sharedProps = map[string]any{}
 }
 
+// resolveSharedData calls MiddlewareConfig.Share at the first time that the shared props are needed.
+// It is not called for the requests that do not render a page, such as redirects.
+// The concurrent callers wait until MiddlewareConfig.Share returns.
+func (i *Inertia) resolveSharedData() (map[string]any, error) {
+	if i.shareFunc == nil {
+		return nil, nil
+	}
+	// The function is called without the lock, because it may call Share.
+	return i.sharedData.get(func() (map[string]any, error) {
+		return i.shareFunc(i.echoContext)
+	})
+}
+
 type VersionFunc func() string
 
 func (i *Inertia) SetVersion(version VersionFunc) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
 	i.version = version
 }
 
 func (i *Inertia) Version() string {
-	return i.version()
+	i.mu.RLock()
+	version := i.version
+	i.mu.RUnlock()
+	return version()
 }
 
 // Location generates 409 response for external redirects
@@ -189,16 +265,19 @@ func (i *Inertia) isPartial(component string) bool {
 }
 
 type Page struct {
-	Component      string         `json:"component"`
-	Props          map[string]any `json:"props"`
-	URL            string         `json:"url"`
-	Version        string         `json:"version"`
-	EncryptHistory bool           `json:"encryptHistory"`
-	ClearHistory   bool           `json:"clearHistory"`
-	DeferredProps  map[string]any `json:"deferredProps,omitempty"`
-	MergeProps     []string       `json:"mergeProps,omitempty"`
-	DeepMergeProps []string       `json:"deepMergeProps,omitempty"`
-	MatchPropsOn   []string       `json:"matchPropsOn,omitempty"`
+	Component      string                    `json:"component"`
+	Props          map[string]any            `json:"props"`
+	URL            string                    `json:"url"`
+	Version        string                    `json:"version"`
+	EncryptHistory bool                      `json:"encryptHistory"`
+	ClearHistory   bool                      `json:"clearHistory"`
+	DeferredProps  map[string]any            `json:"deferredProps,omitempty"`
+	MergeProps     []string                  `json:"mergeProps,omitempty"`
+	PrependProps   []string                  `json:"prependProps,omitempty"`
+	DeepMergeProps []string                  `json:"deepMergeProps,omitempty"`
+	MatchPropsOn   []string                  `json:"matchPropsOn,omitempty"`
+	ScrollProps    map[string]ScrollMetadata `json:"scrollProps,omitempty"`
+	OnceProps      map[string]OnceMetadata   `json:"onceProps,omitempty"`
 }
 
 type RenderContext struct {
@@ -208,8 +287,12 @@ type RenderContext struct {
 	ViewName string
 	// You can set any data you want to ViewData, but the renderer needs to be able to handle it.
 	// For example, the official HTMLRenderer can only accept ViewData as a map[string]any.
+	// If the view data is shared by ShareView or MiddlewareConfig.ShareView, it is merged into ViewData
+	// when ViewData is nil or a map[string]any. The other types of ViewData are not changed.
 	ViewData any
-	Writer   io.Writer
+	// SharedViewData is the view data that is shared by ShareView and MiddlewareConfig.ShareView.
+	SharedViewData map[string]any
+	Writer         io.Writer
 }
 
 func (i *Inertia) Render(component string, propsData any) error {
@@ -217,29 +300,45 @@ func (i *Inertia) Render(component string, propsData any) error {
 }
 
 func (i *Inertia) RenderWithViewData(component string, propsData any, viewData any) error {
-	if i.renderer == nil {
+	return i.render(http.StatusOK, component, propsData, viewData)
+}
+
+func (i *Inertia) render(status int, component string, propsData any, viewData any) error {
+	renderer := i.Renderer()
+	if renderer == nil {
 		return ErrRendererNotRegistered
 	}
 
 	req := i.echoContext.Request()
 	res := i.echoContext.Response()
 
-	props, ok := propsData.(map[string]any)
-	if !ok {
-		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
-			TagName: "prop",
-			Result:  &props,
-		})
-		if err != nil {
-			return err
-		}
-		if err := decoder.Decode(propsData); err != nil {
-			return fmt.Errorf("failed to decode propsData: %w", err)
-		}
+	props, err := decodeProps(propsData, i.propsDecodeMode)
+	if err != nil {
+		return err
 	}
 
 	// merge shared props
-	props = i.mergeProps(i.sharedProps, props)
+	// The "errors" prop is always present so that the client can rely on it.
+	// see https://inertiajs.com/validation
+	sharedData, err := i.resolveSharedData()
+	if err != nil {
+		return err
+	}
+	// The state is read as a snapshot, and the lock is not held while the props are evaluated,
+	// because the callbacks of the props may call the methods of the Inertia.
+	i.mu.RLock()
+	defaultProps := map[string]any{
+		"errors": Always(i.resolveValidationErrors()),
+	}
+	if len(i.flashMessages) > 0 {
+		defaultProps["flash"] = Always(i.copyProps(i.flashMessages))
+	}
+	if len(i.oldInput) > 0 {
+		defaultProps["old"] = Always(i.copyProps(i.oldInput))
+	}
+	props = i.mergeProps(defaultProps, sharedData, i.sharedProps, props)
+	concurrency := i.maxPropConcurrency
+	i.mu.RUnlock()
 
 	// Note:
 	// The official `laravel-inertia` package executes the following methods:
@@ -248,66 +347,104 @@ func (i *Inertia) RenderWithViewData(component string, propsData any, viewData a
 	// but this package does not implement them. This is by design.
 	// I believe that they represent additional layers of data abstraction that don't align with the Go language philosophy.
 
-	// Note:
-	// The official `laravel-inertia` package implements the `resolveValidationErrors` method to extract validation errors from the session.
-	// However, this package does not implement it because Echo does not have a built-in session implementation.
-	// Echo’s official documentation provides information about sessions:
-	// https://echo.labstack.com/docs/middleware/session
-	// Nonetheless, in my opinion, you probably need to implement your own logic to handle sessions.
-	// This means it is difficult to implement a common error-handling logic with sessions, or it may lead to a poor abstraction layer.
-	// It is not match to the Go language philosophy.
-
 	// process partial reloads
 	// https://inertiajs.com/partial-reloads
+	evaluator := &propEvaluator{
+		firstLoad:   !i.isPartial(component),
+		prefetch:    i.isPrefetch,
+		concurrency: concurrency,
+		component:   component,
+		ctx:         req.Context(),
+		onPropError: func(err *PropError) {
+			if i.onPropError != nil {
+				i.onPropError(i.echoContext, err)
+			}
+		},
+	}
 	validProps := i.copyProps(props)
-	validProps = i.resolvePartialProps(component, validProps)
+	validProps, err = i.resolvePartialProps(component, validProps, evaluator)
+	if err != nil {
+		return err
+	}
 	validProps = i.resolveAlwaysProps(props, validProps)
+	validProps = i.resolveExceptOnceProps(validProps)
 
-	if err := evaluateProps(validProps); err != nil {
+	if err := evaluator.evaluateProps(validProps); err != nil {
 		return err
 	}
 
+	// The flags are read after the props are evaluated, so that the callbacks can change them.
+	i.mu.RLock()
+	encryptHistory := i.encryptHistory
+	clearHistory := i.clearHistory
+	i.mu.RUnlock()
+
 	page := &Page{
 		Component:      component,
 		Props:          validProps,
 		URL:            req.URL.String(),
 		Version:        i.Version(),
-		EncryptHistory: i.encryptHistory,
-		ClearHistory:   i.pullClearHistory(),
-		DeferredProps:  i.resolveDeferredProps(component, props),
+		EncryptHistory: encryptHistory,
+		ClearHistory:   clearHistory,
+		DeferredProps:  i.resolveDeferredProps(component, props, evaluator.deferredGroups),
 	}
 
-	mergeProps, deepMergeProps, matchPropsOn := i.resolveMergeProps(props)
+	mergeProps, prependProps, deepMergeProps, matchPropsOn := i.resolveMergeProps(props)
 	page.MergeProps = mergeProps
+	page.PrependProps = prependProps
 	page.DeepMergeProps = deepMergeProps
 	page.MatchPropsOn = matchPropsOn
-
-	res.Header().Set("Vary", HeaderXInertia)
+	page.ScrollProps = i.resolveScrollProps(props)
+	page.OnceProps = i.resolveOnceProps(props)
+
+	// The response differs by the Inertia and prefetch request headers.
+	addVary(res.Header(), HeaderXInertia, HeaderPurpose)
+	if i.isPrefetch && res.Header().Get(echo.HeaderCacheControl) == "" {
+		// The prefetched page is for the user, so it must not be cached by shared caches.
+		res.Header().Set(echo.HeaderCacheControl, "private")
+	}
 
 	if req.Header.Get(HeaderXInertia) != "" {
 		// The request is an Inertia request, so we return JSON response
 		res.Header().Set(HeaderXInertia, "true")
-		return i.echoContext.JSON(http.StatusOK, page)
+		b, err := json.Marshal(page)
+		if err != nil {
+			return err
+		}
+		// The state stored in the FlashStore has been sent to the client.
+		// It is flushed only after the page is rendered, so that it is kept when rendering fails.
+		i.flushFlash()
+		return i.echoContext.JSONBlob(status, b)
 	}
 
 	// The request is a normal request, so we render HTML content.
+	sharedViewData, err := i.resolveSharedViewData()
+	if err != nil {
+		return err
+	}
 	buf := new(bytes.Buffer)
 	renderContext := &RenderContext{
-		Inertia:  i,
-		ViewName: i.rootView,
-		Page:     page,
-		ViewData: viewData,
-		Writer:   buf,
+		Inertia:        i,
+		ViewName:       i.RootView(),
+		Page:           page,
+		ViewData:       mergeSharedViewData(sharedViewData, viewData),
+		SharedViewData: sharedViewData,
+		Writer:         buf,
 	}
-	if err := i.renderer.Render(renderContext); err != nil {
+	if err := renderer.Render(renderContext); err != nil {
 		return err
 	}
-	return i.echoContext.HTMLBlob(http.StatusOK, buf.Bytes())
+	i.flushFlash()
+	return i.echoContext.HTMLBlob(status, buf.Bytes())
 }
 
 func (i *Inertia) mergeProps(props ...map[string]any) map[string]any {
 	merged := map[string]any{}
 	for _, a := range props {
+		if i.sharedPropsMergeMode == SharedPropsMergeDeep {
+			deepMergeProps(merged, expandDotKeys(a))
+			continue
+		}
 		for k, v := range a {
 			merged[k] = v
 		}
@@ -323,16 +460,16 @@ func (i *Inertia) copyProps(props map[string]any) map[string]any {
 	return newProps
 }
 
-func (i *Inertia) resolvePartialProps(component string, validProps map[string]any) map[string]any {
+func (i *Inertia) resolvePartialProps(component string, validProps map[string]any, evaluator *propEvaluator) (map[string]any, error) {
 	if !i.isPartial(component) {
 		// Not a partial request, filter out IgnoreFirstLoad props
 		newProps := make(map[string]any)
 		for key, value := range validProps {
-			if _, isIgnoreFirstLoad := value.(IgnoreFirstLoadProp); !isIgnoreFirstLoad {
+			if !isIgnoredOnFirstLoad(value, i.isPrefetch) {
 				newProps[key] = value
 			}
 		}
-		return newProps
+		return newProps, nil
 	}
 
 	if len(i.onlyProps) > 0 {
@@ -342,6 +479,24 @@ func (i *Inertia) resolvePartialProps(component string, validProps map[string]an
 				newProps[key] = value
 			}
 		}
+		// The keys with dot notation select nested props, like "user.permissions".
+		var paths [][]string
+		for _, key := range i.onlyProps {
+			path := splitPropPath(key)
+			if len(path) > 1 && !inArray(path[0], i.onlyProps) {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) > 0 {
+			evaluator.path = nil
+			picked, _, err := evaluator.pickPropPaths(validProps, paths)
+			if err != nil {
+				return nil, err
+			}
+			for key, v := range picked.(map[string]any) {
+				newProps[key] = v
+			}
+		}
 		validProps = newProps
 	}
 
@@ -351,14 +506,30 @@ func (i *Inertia) resolvePartialProps(component string, validProps map[string]an
 				delete(validProps, key)
 			}
 		}
+		for _, key := range i.exceptProps {
+			path := splitPropPath(key)
+			if len(path) == 1 {
+				continue
+			}
+			value, exists := validProps[path[0]]
+			if !exists {
+				continue
+			}
+			evaluator.path = []string{path[0]}
+			v, err := evaluator.omitPropPath(value, path[1:])
+			if err != nil {
+				return nil, err
+			}
+			validProps[path[0]] = v
+		}
 	}
 
-	return validProps
+	return validProps, nil
 }
 
 func (i *Inertia) resolveAlwaysProps(props, validProps map[string]any) map[string]any {
 	for k, v := range props {
-		if _, ok := v.(*AlwaysProp); ok {
+		if _, ok := unwrapFallback(v).(*AlwaysProp); ok {
 			validProps[k] = v
 		}
 	}
@@ -366,14 +537,20 @@ func (i *Inertia) resolveAlwaysProps(props, validProps map[string]any) map[strin
 	return validProps
 }
 
-func (i *Inertia) resolveDeferredProps(component string, props map[string]any) map[string]any {
+// resolveDeferredProps returns the groups of the deferred props.
+// nestedGroups are the groups of the deferred props nested in the other props.
+// The top-level keys of them are loaded in the groups, and the nested deferred props are evaluated with the whole prop.
+func (i *Inertia) resolveDeferredProps(component string, props map[string]any, nestedGroups map[string][]string) map[string]any {
 	if i.isPartial(component) {
 		return nil
 	}
 
 	groups := make(map[string][]string)
+	for group, keys := range nestedGroups {
+		groups[group] = append(groups[group], keys...)
+	}
 	for key, prop := range props {
-		if deferProp, ok := prop.(*DeferProp); ok {
+		if deferProp, ok := unwrapFallback(prop).(*DeferProp); ok && deferProp.IsDeferred(i.isPrefetch) {
 			group := deferProp.Group()
 			groups[group] = append(groups[group], key)
 		}
@@ -391,31 +568,45 @@ func (i *Inertia) resolveDeferredProps(component string, props map[string]any) m
 	return result
 }
 
-func (i *Inertia) resolveMergeProps(props map[string]any) ([]string, []string, []string) {
+func (i *Inertia) resolveMergeProps(props map[string]any) ([]string, []string, []string, []string) {
 	var mergeProps []string
+	var prependProps []string
 	var deepMergeProps []string
 	var matchOnProps []string
 
 	// Extract props for mergeProps
 	for key, prop := range props {
+		prop = unwrapFallback(prop)
 		if mergeable, ok := prop.(Mergeable); ok && mergeable.ShouldMerge() {
 			// reject the prop if it is in resetProps
 			if inArray(key, i.resetProps) {
 				continue
 			}
 
-			// if onlyProps is specified, skip the prop if it is not in onlyProps
-			if len(i.onlyProps) > 0 && !inArray(key, i.onlyProps) {
+			if !i.isMetadataRequested(key) {
 				continue
 			}
 
-			// skip the prop if it is in exceptProps
-			if inArray(key, i.exceptProps) {
-				continue
+			pathMergeable, isPathMergeable := prop.(PathMergeable)
+			if scrollProp, ok := prop.(*ScrollProp); ok {
+				pathMergeable = scrollProp.mergePathsFor(i.mergeIntent)
 			}
 
 			if mergeable.ShouldDeepMerge() {
 				deepMergeProps = append(deepMergeProps, key)
+			} else if isPathMergeable {
+				if pathMergeable.AppendsAtRoot() {
+					mergeProps = append(mergeProps, key)
+				}
+				if pathMergeable.PrependsAtRoot() {
+					prependProps = append(prependProps, key)
+				}
+				for _, path := range pathMergeable.AppendsAtPaths() {
+					mergeProps = append(mergeProps, key+"."+path)
+				}
+				for _, path := range pathMergeable.PrependsAtPaths() {
+					prependProps = append(prependProps, key+"."+path)
+				}
 			} else {
 				mergeProps = append(mergeProps, key)
 			}
@@ -427,7 +618,77 @@ func (i *Inertia) resolveMergeProps(props map[string]any) ([]string, []string, [
 		}
 	}
 
-	return mergeProps, deepMergeProps, matchOnProps
+	return mergeProps, prependProps, deepMergeProps, matchOnProps
+}
+
+func (i *Inertia) resolveScrollProps(props map[string]any) map[string]ScrollMetadata {
+	var scrollProps map[string]ScrollMetadata
+	for key, prop := range props {
+		scrollProp, ok := unwrapFallback(prop).(*ScrollProp)
+		if !ok || !i.isMetadataRequested(key) {
+			continue
+		}
+
+		if scrollProps == nil {
+			scrollProps = map[string]ScrollMetadata{}
+		}
+		metadata := scrollProp.Metadata()
+		metadata.Reset = inArray(key, i.resetProps)
+		scrollProps[key] = metadata
+	}
+	return scrollProps
+}
+
+// resolveExceptOnceProps filters out the once props that the client already has.
+// The props that are explicitly requested in a partial reload, and the fresh props are sent anyway.
+func (i *Inertia) resolveExceptOnceProps(validProps map[string]any) map[string]any {
+	if len(i.exceptOnceProps) == 0 {
+		return validProps
+	}
+
+	for key, prop := range validProps {
+		onceProp, ok := unwrapFallback(prop).(*OnceProp)
+		if !ok || onceProp.IsFresh() || inArray(key, i.onlyProps) {
+			continue
+		}
+		if inArray(onceProp.onceKey(key), i.exceptOnceProps) {
+			delete(validProps, key)
+		}
+	}
+	return validProps
+}
+
+func (i *Inertia) resolveOnceProps(props map[string]any) map[string]OnceMetadata {
+	var onceProps map[string]OnceMetadata
+	// The expiration times of ExpiresIn are computed from the same time in a page.
+	now := time.Now()
+	for key, prop := range props {
+		onceProp, ok := unwrapFallback(prop).(*OnceProp)
+		if !ok || !i.isMetadataRequested(key) {
+			continue
+		}
+
+		if onceProps == nil {
+			onceProps = map[string]OnceMetadata{}
+		}
+		onceProps[onceProp.onceKey(key)] = onceProp.metadata(key, now)
+	}
+	return onceProps
+}
+
+// isMetadataRequested reports whether the metadata of the prop should be sent in a partial reload.
+func (i *Inertia) isMetadataRequested(key string) bool {
+	// if onlyProps is specified, skip the prop if neither it nor its nested props are in onlyProps
+	if len(i.onlyProps) > 0 && !inArray(key, i.onlyProps) && !hasSelectedDescendant(key, i.onlyProps) {
+		return false
+	}
+
+	// skip the prop if it is in exceptProps
+	if inArray(key, i.exceptProps) {
+		return false
+	}
+
+	return true
 }
 
 func SetRootView(c echo.Context, name string) {
@@ -470,6 +731,10 @@ func ClearHistory(c echo.Context) {
 	MustGet(c).ClearHistory()
 }
 
+func SetMaxPropConcurrency(c echo.Context, n int) {
+	MustGet(c).SetMaxPropConcurrency(n)
+}
+
 func Render(c echo.Context, component string, props any) error {
 	return MustGet(c).Render(component, props)
 }
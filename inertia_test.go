@@ -1 +1,883 @@
 package inertia
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// testRenderPage renders the component with the props as an Inertia request and returns the page object.
+func testRenderPage(t *testing.T, headers map[string]string, component string, props any) *Page {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderXInertia, "true")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	e := echo.New()
+	c := e.NewContext(req, rec)
+	m := MiddlewareWithConfig(MiddlewareConfig{
+		Renderer:    testNewMockRenderer(t, func(ctx *RenderContext) error { return nil }),
+		VersionFunc: func() string { return "" },
+		FlashStore:  NewMemoryFlashStore(),
+	})
+
+	err := m(func(c echo.Context) error {
+		return Render(c, component, props)
+	})(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := &Page{}
+	if err := json.Unmarshal(rec.Body.Bytes(), page); err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func TestRender_ScrollProps(t *testing.T) {
+	newProps := func() map[string]any {
+		return map[string]any{
+			"users": Scroll(map[string]any{
+				"data": []any{"user1", "user2"},
+			}, ScrollMetadata{
+				PreviousPage: 1,
+				CurrentPage:  2,
+				NextPage:     3,
+			}),
+		}
+	}
+
+	t.Run("append", func(t *testing.T) {
+		page := testRenderPage(t, nil, "Users/Index", newProps())
+
+		if !testDeepEqual(t, page.MergeProps, []string{"users.data"}) {
+			t.Errorf("unexpected mergeProps: %v", page.MergeProps)
+		}
+		if len(page.PrependProps) != 0 {
+			t.Errorf("unexpected prependProps: %v", page.PrependProps)
+		}
+		metadata := page.ScrollProps["users"]
+		if metadata.PageName != "page" || metadata.PreviousPage != float64(1) || metadata.CurrentPage != float64(2) || metadata.NextPage != float64(3) || metadata.Reset {
+			t.Errorf("unexpected scrollProps: %+v", page.ScrollProps)
+		}
+		if !testDeepEqual(t, page.Props["users"], map[string]any{"data": []any{"user1", "user2"}}) {
+			t.Errorf("unexpected props: %v", page.Props["users"])
+		}
+	})
+
+	t.Run("prepend", func(t *testing.T) {
+		page := testRenderPage(t, map[string]string{
+			HeaderXInertiaInfiniteScrollMergeIntent: "prepend",
+		}, "Users/Index", newProps())
+
+		if len(page.MergeProps) != 0 {
+			t.Errorf("unexpected mergeProps: %v", page.MergeProps)
+		}
+		if !testDeepEqual(t, page.PrependProps, []string{"users.data"}) {
+			t.Errorf("unexpected prependProps: %v", page.PrependProps)
+		}
+	})
+
+	t.Run("wrapper and match on", func(t *testing.T) {
+		page := testRenderPage(t, nil, "Users/Index", map[string]any{
+			"users": Scroll(map[string]any{"items": []any{"user1"}}, ScrollMetadata{}).Wrapper("items").MatchOn("id"),
+		})
+
+		if !testDeepEqual(t, page.MergeProps, []string{"users.items"}) {
+			t.Errorf("unexpected mergeProps: %v", page.MergeProps)
+		}
+		if !testDeepEqual(t, page.MatchPropsOn, []string{"users.id"}) {
+			t.Errorf("unexpected matchPropsOn: %v", page.MatchPropsOn)
+		}
+	})
+
+	t.Run("shared between requests", func(t *testing.T) {
+		// The merge intent of a request does not change the prop, which may be shared between requests.
+		props := newProps()
+		testRenderPage(t, map[string]string{
+			HeaderXInertiaInfiniteScrollMergeIntent: "prepend",
+		}, "Users/Index", props)
+		if paths := props["users"].(*ScrollProp).PrependsAtPaths(); len(paths) != 0 {
+			t.Errorf("expected the prop not to be changed, got prepend paths %v", paths)
+		}
+		page := testRenderPage(t, nil, "Users/Index", props)
+
+		if !testDeepEqual(t, page.MergeProps, []string{"users.data"}) {
+			t.Errorf("unexpected mergeProps: %v", page.MergeProps)
+		}
+		if len(page.PrependProps) != 0 {
+			t.Errorf("unexpected prependProps: %v", page.PrependProps)
+		}
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		page := testRenderPage(t, map[string]string{
+			HeaderXInertiaPartialComponent: "Users/Index",
+			HeaderXInertiaPartialData:      "users",
+			HeaderXInertiaReset:            "users",
+		}, "Users/Index", newProps())
+
+		if len(page.MergeProps) != 0 {
+			t.Errorf("unexpected mergeProps: %v", page.MergeProps)
+		}
+		if !page.ScrollProps["users"].Reset {
+			t.Errorf("expected reset to be true: %+v", page.ScrollProps)
+		}
+	})
+}
+
+func TestRender_MergePaths(t *testing.T) {
+	tests := []struct {
+		name            string
+		headers         map[string]string
+		props           map[string]any
+		expectedMerge   []string
+		expectedPrepend []string
+	}{
+		{
+			name:          "append at root",
+			props:         map[string]any{"tags": Merge([]string{"a"})},
+			expectedMerge: []string{"tags"},
+		},
+		{
+			name:            "prepend at root",
+			props:           map[string]any{"messages": Merge([]string{"a"}).Prepend()},
+			expectedPrepend: []string{"messages"},
+		},
+		{
+			name: "append at path",
+			props: map[string]any{"feed": Merge(map[string]any{
+				"data": map[string]any{"items": []string{"a"}, "meta": "replaced"},
+			}).Append("data.items")},
+			expectedMerge: []string{"feed.data.items"},
+		},
+		{
+			name:            "append and prepend at paths",
+			props:           map[string]any{"feed": Merge(map[string]any{}).Append("newer").Prepend("older")},
+			expectedMerge:   []string{"feed.newer"},
+			expectedPrepend: []string{"feed.older"},
+		},
+		{
+			name: "deferred prop prepends at path",
+			headers: map[string]string{
+				HeaderXInertiaPartialComponent: "Chat",
+				HeaderXInertiaPartialData:      "messages",
+			},
+			props: map[string]any{"messages": Defer(func() (any, error) {
+				return map[string]any{"data": []string{"a"}}, nil
+			}).Prepend("data")},
+			expectedPrepend: []string{"messages.data"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := testRenderPage(t, tt.headers, "Chat", tt.props)
+
+			if len(page.MergeProps) != len(tt.expectedMerge) || (len(tt.expectedMerge) > 0 && !testDeepEqual(t, page.MergeProps, tt.expectedMerge)) {
+				t.Errorf("expected mergeProps %v, got %v", tt.expectedMerge, page.MergeProps)
+			}
+			if len(page.PrependProps) != len(tt.expectedPrepend) || (len(tt.expectedPrepend) > 0 && !testDeepEqual(t, page.PrependProps, tt.expectedPrepend)) {
+				t.Errorf("expected prependProps %v, got %v", tt.expectedPrepend, page.PrependProps)
+			}
+		})
+	}
+}
+
+type testProfileBase struct {
+	ID int `json:"id"`
+}
+
+type testProfile struct {
+	testProfileBase
+	Bio     string `json:"bio"`
+	Website string `json:"website,omitempty"`
+	Secret  string `json:"-"`
+	Teams   any    `json:"teams"`
+}
+
+func TestRender_PartialReloadPaths(t *testing.T) {
+	evaluated := map[string]int{}
+	newProps := func() map[string]any {
+		return map[string]any{
+			"user": map[string]any{
+				"name": "John",
+				"permissions": Optional(func() (any, error) {
+					evaluated["permissions"]++
+					return []string{"edit"}, nil
+				}),
+				"teams": func() any {
+					evaluated["teams"]++
+					return []string{"team1"}
+				},
+			},
+			"profile": func() any {
+				evaluated["profile"]++
+				return &testProfile{
+					testProfileBase: testProfileBase{ID: 1},
+					Bio:             "Gopher",
+					Secret:          "secret",
+					Teams: Optional(func() (any, error) {
+						evaluated["profileTeams"]++
+						return []string{"team1"}, nil
+					}),
+				}
+			},
+			"labels": map[string]string{"role": "admin", "plan": "pro"},
+			"stats": func() any {
+				evaluated["stats"]++
+				return map[string]any{"visits": 10, "views": 20}
+			},
+		}
+	}
+
+	tests := []struct {
+		name              string
+		headers           map[string]string
+		expectedProps     string
+		expectedEvaluated []string
+	}{
+		{
+			name: "only a nested prop",
+			headers: map[string]string{
+				HeaderXInertiaPartialComponent: "Users/Show",
+				HeaderXInertiaPartialData:      "user.permissions",
+			},
+			expectedProps:     `{"errors":{},"user":{"permissions":["edit"]}}`,
+			expectedEvaluated: []string{"permissions"},
+		},
+		{
+			name: "only a prop inside a callback",
+			headers: map[string]string{
+				HeaderXInertiaPartialComponent: "Users/Show",
+				HeaderXInertiaPartialData:      "stats.visits,user.name",
+			},
+			expectedProps:     `{"errors":{},"stats":{"visits":10},"user":{"name":"John"}}`,
+			expectedEvaluated: []string{"stats"},
+		},
+		{
+			// The callback is called once for the nested props that share it.
+			name: "only the props inside the same callback",
+			headers: map[string]string{
+				HeaderXInertiaPartialComponent: "Users/Show",
+				HeaderXInertiaPartialData:      "stats.visits,stats.views",
+			},
+			expectedProps:     `{"errors":{},"stats":{"views":20,"visits":10}}`,
+			expectedEvaluated: []string{"stats"},
+		},
+		{
+			// The structs are walked by the field names of encoding/json.
+			name: "only the fields of a struct",
+			headers: map[string]string{
+				HeaderXInertiaPartialComponent: "Users/Show",
+				HeaderXInertiaPartialData:      "profile.id,profile.teams,profile.website,profile.Secret",
+			},
+			expectedProps:     `{"errors":{},"profile":{"id":1,"teams":["team1"]}}`,
+			expectedEvaluated: []string{"profile", "profileTeams"},
+		},
+		{
+			name: "except a field of a struct",
+			headers: map[string]string{
+				HeaderXInertiaPartialComponent: "Users/Show",
+				HeaderXInertiaPartialExcept:    "profile.teams,user,stats,labels",
+			},
+			expectedProps:     `{"errors":{},"profile":{"bio":"Gopher","id":1}}`,
+			expectedEvaluated: []string{"profile"},
+		},
+		{
+			name: "only a value of a typed map",
+			headers: map[string]string{
+				HeaderXInertiaPartialComponent: "Users/Show",
+				HeaderXInertiaPartialData:      "labels.role",
+			},
+			expectedProps:     `{"errors":{},"labels":{"role":"admin"}}`,
+			expectedEvaluated: []string{},
+		},
+		{
+			name: "only the parent and a nested prop",
+			headers: map[string]string{
+				HeaderXInertiaPartialComponent: "Users/Show",
+				HeaderXInertiaPartialData:      "user,user.name",
+			},
+			expectedProps:     `{"errors":{},"user":{"name":"John","permissions":["edit"],"teams":["team1"]}}`,
+			expectedEvaluated: []string{"permissions", "teams"},
+		},
+		{
+			name: "only a missing nested prop",
+			headers: map[string]string{
+				HeaderXInertiaPartialComponent: "Users/Show",
+				HeaderXInertiaPartialData:      "user.missing,user.name.first",
+			},
+			expectedProps:     `{"errors":{}}`,
+			expectedEvaluated: []string{},
+		},
+		{
+			name: "except a nested prop",
+			headers: map[string]string{
+				HeaderXInertiaPartialComponent: "Users/Show",
+				HeaderXInertiaPartialExcept:    "user.permissions,user.teams,stats,profile,labels",
+			},
+			expectedProps:     `{"errors":{},"user":{"name":"John"}}`,
+			expectedEvaluated: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated = map[string]int{}
+			props := newProps()
+			page := testRenderPage(t, tt.headers, "Users/Show", props)
+
+			b, err := json.Marshal(page.Props)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.expectedProps {
+				t.Errorf("expected props %s, got %s", tt.expectedProps, b)
+			}
+			if len(evaluated) != len(tt.expectedEvaluated) {
+				t.Errorf("expected evaluated props %v, got %v", tt.expectedEvaluated, evaluated)
+			}
+			for _, key := range tt.expectedEvaluated {
+				if evaluated[key] != 1 {
+					t.Errorf("expected %q to be evaluated once, got %d", key, evaluated[key])
+				}
+			}
+			if _, ok := props["user"].(map[string]any)["permissions"].(*OptionalProp); !ok {
+				t.Error("expected the given props not to be modified")
+			}
+		})
+	}
+}
+
+func TestRender_NestedProps(t *testing.T) {
+	newProps := func() map[string]any {
+		return map[string]any{
+			"dashboard": map[string]any{
+				"title": "Dashboard",
+				"visits": func() any {
+					return 10
+				},
+				"report": Optional(func() (any, error) {
+					return "report", nil
+				}),
+				"sales": DeferWithGroup(func() (any, error) {
+					return 100, nil
+				}, "stats"),
+			},
+		}
+	}
+
+	t.Run("first load", func(t *testing.T) {
+		props := newProps()
+		page := testRenderPage(t, nil, "Dashboard", props)
+
+		b, err := json.Marshal(page.Props)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := `{"dashboard":{"title":"Dashboard","visits":10},"errors":{}}`; string(b) != expected {
+			t.Errorf("expected props %s, got %s", expected, b)
+		}
+		if !testDeepEqual(t, page.DeferredProps, map[string]any{"stats": []any{"dashboard"}}) {
+			t.Errorf("expected deferredProps to have the dashboard prop, got %v", page.DeferredProps)
+		}
+		if _, ok := props["dashboard"].(map[string]any)["visits"].(func() any); !ok {
+			t.Error("expected the given props not to be modified")
+		}
+	})
+
+	t.Run("partial reload", func(t *testing.T) {
+		page := testRenderPage(t, map[string]string{
+			HeaderXInertiaPartialComponent: "Dashboard",
+			HeaderXInertiaPartialData:      "dashboard",
+		}, "Dashboard", newProps())
+
+		b, err := json.Marshal(page.Props)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := `{"dashboard":{"report":"report","sales":100,"title":"Dashboard","visits":10},"errors":{}}`; string(b) != expected {
+			t.Errorf("expected props %s, got %s", expected, b)
+		}
+		if page.DeferredProps != nil {
+			t.Errorf("expected no deferredProps, got %v", page.DeferredProps)
+		}
+	})
+}
+
+func TestRender_PropError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderXInertia, "true")
+	rec := httptest.NewRecorder()
+	e := echo.New()
+	c := e.NewContext(req, rec)
+	m := MiddlewareWithConfig(MiddlewareConfig{
+		Renderer:    testNewMockRenderer(t, func(ctx *RenderContext) error { return nil }),
+		VersionFunc: func() string { return "" },
+		FlashStore:  NewMemoryFlashStore(),
+	})
+
+	err := m(func(c echo.Context) error {
+		return Render(c, "Users/Show", map[string]any{
+			"user": map[string]any{
+				"permissions": func() any { panic("boom") },
+			},
+		})
+	})(c)
+
+	var pe *PropError
+	if !errors.As(err, &pe) {
+		t.Fatalf("expected PropError, got %v", err)
+	}
+	if pe.Path != "user.permissions" || pe.Component != "Users/Show" {
+		t.Errorf("expected the prop user.permissions of Users/Show, got %q of %q", pe.Path, pe.Component)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected no response, got %s", rec.Body.String())
+	}
+}
+
+func TestRender_FallbackProps(t *testing.T) {
+	var reported []string
+	newMiddleware := func() echo.MiddlewareFunc {
+		return MiddlewareWithConfig(MiddlewareConfig{
+			Renderer:    testNewMockRenderer(t, func(ctx *RenderContext) error { return nil }),
+			VersionFunc: func() string { return "" },
+			FlashStore:  NewMemoryFlashStore(),
+			OnPropError: func(c echo.Context, err *PropError) {
+				reported = append(reported, err.Path)
+			},
+		})
+	}
+	props := map[string]any{
+		"counter": Fallback(func() (any, error) {
+			return nil, errors.New("counter error")
+		}, 0),
+		"recommendations": Fallback(Defer(func() (any, error) {
+			return nil, errors.New("recommendations error")
+		}), []any{}),
+	}
+
+	render := func(headers map[string]string) *Page {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(HeaderXInertia, "true")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		if err := newMiddleware()(func(c echo.Context) error {
+			return Render(c, "Home", props)
+		})(c); err != nil {
+			t.Fatal(err)
+		}
+
+		page := &Page{}
+		if err := json.Unmarshal(rec.Body.Bytes(), page); err != nil {
+			t.Fatal(err)
+		}
+		return page
+	}
+
+	page := render(nil)
+	if page.Props["counter"] != float64(0) {
+		t.Errorf("expected the fallback value of counter, got %v", page.Props["counter"])
+	}
+	if _, ok := page.Props["recommendations"]; ok {
+		t.Error("expected the deferred prop with fallback not to be included on first load")
+	}
+	if !testDeepEqual(t, page.DeferredProps, map[string]any{"default": []any{"recommendations"}}) {
+		t.Errorf("expected recommendations to be deferred, got %v", page.DeferredProps)
+	}
+
+	page = render(map[string]string{
+		HeaderXInertiaPartialComponent: "Home",
+		HeaderXInertiaPartialData:      "recommendations",
+	})
+	if !testDeepEqual(t, page.Props["recommendations"], []any{}) {
+		t.Errorf("expected the fallback value of recommendations, got %v", page.Props["recommendations"])
+	}
+
+	if !testDeepEqual(t, reported, []string{"counter", "recommendations"}) {
+		t.Errorf("expected the errors to be reported, got %v", reported)
+	}
+}
+
+func TestOnceProp_ExpiresIn(t *testing.T) {
+	// The prop is created once and reused, like a package-level variable.
+	p := Once("translations").ExpiresIn(time.Hour)
+
+	for _, now := range []time.Time{time.UnixMilli(1700000000000), time.UnixMilli(1800000000000)} {
+		metadata := p.metadata("translations", now)
+		if expected := now.Add(time.Hour).UnixMilli(); metadata.ExpiresAt == nil || *metadata.ExpiresAt != expected {
+			t.Errorf("expected the expiration time %d, got %v", expected, metadata.ExpiresAt)
+		}
+	}
+
+	before := time.Now()
+	page := testRenderPage(t, nil, "Home", map[string]any{"translations": p})
+	metadata := page.OnceProps["translations"]
+	if metadata.ExpiresAt == nil || *metadata.ExpiresAt < before.Add(time.Hour).UnixMilli() {
+		t.Errorf("expected the expiration time to be computed at render time, got %v", metadata.ExpiresAt)
+	}
+
+	if expiresAt := Once("plan").ExpiresIn(time.Hour).Until(time.UnixMilli(1700000000000)).ExpiresAt(); !expiresAt.Equal(time.UnixMilli(1700000000000)) {
+		t.Errorf("expected Until to override ExpiresIn, got %v", expiresAt)
+	}
+}
+
+func TestRender_OnceProps(t *testing.T) {
+	expiresAt := time.UnixMilli(1700000000000)
+	newProps := func() map[string]any {
+		return map[string]any{
+			"translations": Once(func() any { return "translations" }),
+			"plan":         Once("pro").As("billing.plan").Until(expiresAt),
+			"flags":        Once("flags").Fresh(),
+		}
+	}
+
+	tests := []struct {
+		name          string
+		headers       map[string]string
+		expectedProps []string
+	}{
+		{
+			name:          "first visit",
+			expectedProps: []string{"translations", "plan", "flags"},
+		},
+		{
+			name: "the client has the props",
+			headers: map[string]string{
+				HeaderXInertiaExceptOnceProps: "translations,billing.plan,flags",
+			},
+			expectedProps: []string{"flags"},
+		},
+		{
+			name: "the client has the props with the default keys",
+			headers: map[string]string{
+				HeaderXInertiaExceptOnceProps: "plan",
+			},
+			expectedProps: []string{"translations", "plan", "flags"},
+		},
+		{
+			name: "partial reload requests the prop explicitly",
+			headers: map[string]string{
+				HeaderXInertiaPartialComponent: "Home",
+				HeaderXInertiaPartialData:      "translations,plan",
+				HeaderXInertiaExceptOnceProps:  "translations,billing.plan",
+			},
+			expectedProps: []string{"translations", "plan"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := testRenderPage(t, tt.headers, "Home", newProps())
+
+			for _, key := range []string{"translations", "plan", "flags"} {
+				_, ok := page.Props[key]
+				if expected := inArray(key, tt.expectedProps); ok != expected {
+					t.Errorf("expected %s to be included: %v, got %v", key, expected, ok)
+				}
+			}
+
+			metadata, ok := page.OnceProps["billing.plan"]
+			if !ok || metadata.Prop != "plan" || metadata.ExpiresAt == nil || *metadata.ExpiresAt != expiresAt.UnixMilli() {
+				t.Errorf("expected the metadata of billing.plan, got %+v", page.OnceProps)
+			}
+			if tt.headers[HeaderXInertiaPartialData] == "" {
+				if metadata := page.OnceProps["translations"]; metadata.Prop != "translations" || metadata.ExpiresAt != nil {
+					t.Errorf("expected the metadata of translations, got %+v", page.OnceProps)
+				}
+			}
+		})
+	}
+}
+
+func TestRender_StructTags(t *testing.T) {
+	type BaseProps struct {
+		Flash map[string]any `prop:"flash,always,omitempty"`
+	}
+	type usersIndexProps struct {
+		BaseProps `prop:",squash"`
+		Users     []string            `prop:"users,merge,matchOn=id"`
+		Feed      []string            `prop:"feed,prepend"`
+		Roles     func() (any, error) `prop:"roles,defer=sidebar"`
+		Stats     func() any          `prop:"stats,optional"`
+		Settings  map[string]any      `prop:"settings,deepMerge"`
+		Note      string              `prop:"note,omitempty"`
+	}
+	props := &usersIndexProps{
+		BaseProps: BaseProps{Flash: map[string]any{"message": "saved"}},
+		Users:     []string{"John"},
+		Feed:      []string{"post"},
+		Roles:     func() (any, error) { return []string{"admin"}, nil },
+		Stats:     func() any { return 10 },
+		Settings:  map[string]any{"theme": "dark"},
+	}
+
+	page := testRenderPage(t, nil, "Users/Index", props)
+
+	b, err := json.Marshal(page.Props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"errors":{},"feed":["post"],"flash":{"message":"saved"},"settings":{"theme":"dark"},"users":["John"]}`; string(b) != expected {
+		t.Errorf("expected props %s, got %s", expected, b)
+	}
+	if !testDeepEqual(t, page.DeferredProps, map[string]any{"sidebar": []any{"roles"}}) {
+		t.Errorf("expected deferredProps, got %v", page.DeferredProps)
+	}
+	if !testDeepEqual(t, page.MergeProps, []string{"users"}) {
+		t.Errorf("expected mergeProps, got %v", page.MergeProps)
+	}
+	if !testDeepEqual(t, page.PrependProps, []string{"feed"}) {
+		t.Errorf("expected prependProps, got %v", page.PrependProps)
+	}
+	if !testDeepEqual(t, page.DeepMergeProps, []string{"settings"}) {
+		t.Errorf("expected deepMergeProps, got %v", page.DeepMergeProps)
+	}
+	if !testDeepEqual(t, page.MatchPropsOn, []string{"users.id"}) {
+		t.Errorf("expected matchPropsOn, got %v", page.MatchPropsOn)
+	}
+
+	page = testRenderPage(t, map[string]string{
+		HeaderXInertiaPartialComponent: "Users/Index",
+		HeaderXInertiaPartialData:      "roles,stats",
+	}, "Users/Index", props)
+	if b, _ := json.Marshal(page.Props); string(b) != `{"errors":{},"flash":{"message":"saved"},"roles":["admin"],"stats":10}` {
+		t.Errorf("expected the deferred and optional props with always props, got %s", b)
+	}
+}
+
+func TestShare_Lazy(t *testing.T) {
+	calls := 0
+	userLoaded := false
+	newEcho := func() *echo.Echo {
+		e := echo.New()
+		e.Use(MiddlewareWithConfig(MiddlewareConfig{
+			Renderer:    testNewMockRenderer(t, func(ctx *RenderContext) error { return nil }),
+			VersionFunc: func() string { return "" },
+			FlashStore:  NewMemoryFlashStore(),
+			Share: func(c echo.Context) (map[string]any, error) {
+				calls++
+				// Share can be called in the function.
+				Share(c, map[string]any{"locale": "en"})
+				return map[string]any{
+					"appName": "app",
+					"locale":  "ja",
+					"auth": func() any {
+						userLoaded = true
+						return map[string]any{"user": "John"}
+					},
+				}, nil
+			},
+		}))
+		e.GET("/", func(c echo.Context) error {
+			Share(c, map[string]any{"appName": "overridden"})
+			return Render(c, "Home", map[string]any{"posts": []string{"post"}})
+		})
+		e.POST("/", func(c echo.Context) error {
+			return c.Redirect(http.StatusFound, "/")
+		})
+		return e
+	}
+
+	serve := func(method string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		req.Header.Set(HeaderXInertia, "true")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		newEcho().ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("redirect", func(t *testing.T) {
+		calls = 0
+		serve(http.MethodPost, nil)
+		if calls != 0 {
+			t.Errorf("expected Share not to be called, got %d calls", calls)
+		}
+	})
+
+	t.Run("partial reload", func(t *testing.T) {
+		calls, userLoaded = 0, false
+		rec := serve(http.MethodGet, map[string]string{
+			HeaderXInertiaPartialComponent: "Home",
+			HeaderXInertiaPartialData:      "posts,appName,locale",
+		})
+		if calls != 1 {
+			t.Errorf("expected Share to be called once, got %d calls", calls)
+		}
+		if userLoaded {
+			t.Error("expected the auth prop not to be evaluated")
+		}
+		if expected := `"props":{"appName":"overridden","errors":{},"locale":"en","posts":["post"]}`; !strings.Contains(rec.Body.String(), expected) {
+			t.Errorf("expected %s, got %s", expected, rec.Body.String())
+		}
+	})
+
+	t.Run("full visit", func(t *testing.T) {
+		userLoaded = false
+		rec := serve(http.MethodGet, nil)
+		if !userLoaded {
+			t.Error("expected the auth prop to be evaluated")
+		}
+		if expected := `"auth":{"user":"John"}`; !strings.Contains(rec.Body.String(), expected) {
+			t.Errorf("expected %s, got %s", expected, rec.Body.String())
+		}
+	})
+}
+
+func TestRender_ConcurrentCallbacks(t *testing.T) {
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Renderer:           testNewMockRenderer(t, func(ctx *RenderContext) error { return nil }),
+		VersionFunc:        func() string { return "" },
+		FlashStore:         NewMemoryFlashStore(),
+		MaxPropConcurrency: 8,
+	}))
+	e.GET("/", func(c echo.Context) error {
+		i := MustGet(c)
+		Share(c, map[string]any{"appName": "app"})
+		props := map[string]any{}
+		var started sync.WaitGroup
+		started.Add(4)
+		for n := 0; n < 4; n++ {
+			key := fmt.Sprintf("prop%d", n)
+			props[key] = func() any {
+				// Wait until all the callbacks have started, so that they use the Inertia at the same time.
+				started.Done()
+				started.Wait()
+				i.Flash(key, n)
+				i.WithErrors(ValidationErrors{key: "invalid"})
+				i.ClearHistory()
+				i.EncryptHistory(true)
+				i.SetRootView("app.html")
+				i.Share(map[string]any{key: n})
+				shared := i.Shared()
+				shared[key] = "modified"
+				return i.Old(key)
+			}
+		}
+		return i.Render("Home", props)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderXInertia, "true")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	for _, expected := range []string{`"encryptHistory":true`, `"clearHistory":true`, `"appName":"app"`} {
+		if !strings.Contains(rec.Body.String(), expected) {
+			t.Errorf("expected %s, got %s", expected, rec.Body.String())
+		}
+	}
+}
+
+func TestRender_RootViewFunc(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		setRootView string
+		// setAfterRootView calls SetRootView after RootView resolves the root template by the function.
+		setAfterRootView bool
+		expected         string
+		expectedCalls    int
+	}{
+		{
+			name:          "resolved by the function",
+			host:          "admin.example.com",
+			expected:      "admin.html",
+			expectedCalls: 1,
+		},
+		{
+			name:          "fallback to RootView",
+			host:          "example.com",
+			expected:      "app.html",
+			expectedCalls: 1,
+		},
+		{
+			name:        "SetRootView takes precedence",
+			host:        "admin.example.com",
+			setRootView: "widget.html",
+			expected:    "widget.html",
+		},
+		{
+			name:             "SetRootView after the function",
+			host:             "admin.example.com",
+			setRootView:      "widget.html",
+			setAfterRootView: true,
+			expected:         "widget.html",
+			expectedCalls:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var viewName string
+			calls := 0
+			e := echo.New()
+			e.Use(MiddlewareWithConfig(MiddlewareConfig{
+				Renderer: testNewMockRenderer(t, func(ctx *RenderContext) error {
+					viewName = ctx.ViewName
+					return nil
+				}),
+				VersionFunc: func() string { return "" },
+				FlashStore:  NewMemoryFlashStore(),
+				RootView:    "app.html",
+				RootViewFunc: func(c echo.Context) string {
+					calls++
+					if strings.HasPrefix(c.Request().Host, "admin.") {
+						return "admin.html"
+					}
+					return ""
+				},
+			}))
+			e.GET("/", func(c echo.Context) error {
+				if tt.setRootView != "" && !tt.setAfterRootView {
+					SetRootView(c, tt.setRootView)
+				}
+				// The root template is resolved once, and reused by Render.
+				_ = RootView(c)
+				if tt.setAfterRootView {
+					SetRootView(c, tt.setRootView)
+				}
+				return Render(c, "Home", map[string]any{})
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			if viewName != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, viewName)
+			}
+			if calls != tt.expectedCalls {
+				t.Errorf("expected RootViewFunc to be called %d times, got %d", tt.expectedCalls, calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package inertia
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// Method spoofing
+// Inertia.js sends file uploads as POST requests with the "_method" field,
+// because browsers can not send multipart/form-data with PUT, PATCH and DELETE methods.
+// see https://inertiajs.com/file-uploads#multipart-limitations
+
+const methodOverrideField = "_method"
+
+type MethodOverrideConfig struct {
+	Skipper middleware.Skipper
+	// FieldName is the name of the form field that has the spoofed method.
+	FieldName string
+}
+
+var DefaultMethodOverrideConfig = MethodOverrideConfig{
+	Skipper:   middleware.DefaultSkipper,
+	FieldName: methodOverrideField,
+}
+
+// MethodOverride returns a middleware that overrides the method of a POST request with the "_method" form field.
+// It must be registered with Echo#Pre, so that the method is overridden before routing.
+//
+//	e.Pre(inertia.MethodOverride())
+func MethodOverride() echo.MiddlewareFunc {
+	return MethodOverrideWithConfig(DefaultMethodOverrideConfig)
+}
+
+func MethodOverrideWithConfig(config MethodOverrideConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = DefaultMethodOverrideConfig.Skipper
+	}
+	if config.FieldName == "" {
+		config.FieldName = DefaultMethodOverrideConfig.FieldName
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			if req.Method == http.MethodPost {
+				if m := spoofedMethod(c.FormValue(config.FieldName)); m != "" {
+					req.Method = m
+				}
+			}
+			return next(c)
+		}
+	}
+}
+
+// spoofedMethod returns the method if it is allowed to be spoofed.
+func spoofedMethod(method string) string {
+	method = strings.ToUpper(method)
+	if inArray(method, []string{http.MethodPut, http.MethodPatch, http.MethodDelete}) {
+		return method
+	}
+	return ""
+}
+
+// requestMethod returns the method of the request.
+// If the request is a spoofed POST request whose form has already been parsed, it returns the spoofed method.
+// It covers the case that the handler reads the "_method" field by itself without the MethodOverride middleware.
+func requestMethod(req *http.Request) string {
+	if req.Method == http.MethodPost && req.PostForm != nil {
+		if m := spoofedMethod(req.PostForm.Get(methodOverrideField)); m != "" {
+			return m
+		}
+	}
+	return req.Method
+}
@@ -0,0 +1,111 @@
+package inertia
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func testNewMultipartRequest(t *testing.T, target string, fields map[string]string) *http.Request {
+	t.Helper()
+
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, target, body)
+	req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+	return req
+}
+
+func TestMethodOverride(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		expectedStatus int
+	}{
+		{
+			name:           "put",
+			method:         "put",
+			expectedStatus: http.StatusSeeOther,
+		},
+		{
+			name:           "patch",
+			method:         "PATCH",
+			expectedStatus: http.StatusSeeOther,
+		},
+		{
+			name:           "not allowed method",
+			method:         "GET",
+			expectedStatus: http.StatusFound,
+		},
+		{
+			name:           "no method",
+			expectedStatus: http.StatusFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Pre(MethodOverride())
+			e.Use(MiddlewareWithConfig(MiddlewareConfig{
+				VersionFunc: func() string { return "" },
+				FlashStore:  NewMemoryFlashStore(),
+			}))
+			redirect := func(c echo.Context) error {
+				return c.Redirect(http.StatusFound, "/users")
+			}
+			e.POST("/users/1", redirect)
+			e.PUT("/users/1", redirect)
+			e.PATCH("/users/1", redirect)
+
+			fields := map[string]string{"name": "John"}
+			if tt.method != "" {
+				fields["_method"] = tt.method
+			}
+			req := testNewMultipartRequest(t, "/users/1", fields)
+			req.Header.Set(HeaderXInertia, "true")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestChangeRedirectCode_SpoofedMethodWithoutMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		VersionFunc: func() string { return "" },
+		FlashStore:  NewMemoryFlashStore(),
+	}))
+	e.POST("/users/1", func(c echo.Context) error {
+		if c.FormValue("_method") != "put" {
+			t.Errorf("expected the spoofed method, got %q", c.FormValue("_method"))
+		}
+		return c.Redirect(http.StatusFound, "/users")
+	})
+
+	req := testNewMultipartRequest(t, "/users/1", map[string]string{"_method": "put"})
+	req.Header.Set(HeaderXInertia, "true")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusSeeOther {
+		t.Errorf("expected status 303, got %d", rec.Code)
+	}
+}
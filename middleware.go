@@ -1,8 +1,11 @@
 package inertia
 
 import (
+	"crypto/rand"
+	"log"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -18,16 +21,61 @@ type MiddlewareConfig struct {
 	// The root template that's loaded on the first page visit.
 	// see https://inertiajs.com/server-side-setup#root-template
 	RootView string
+	// RootViewFunc determines the root template per request, such as by the route group, the host or a header.
+	// If it returns an empty string, RootView is used. SetRootView in a handler takes precedence over it.
+	// It is called at most once per request, when the root template is needed for the first time.
+	RootViewFunc RootViewFunc
 	// Determines the current asset version.
 	// see https://inertiajs.com/asset-versioning
 	VersionFunc func() string
 	// Defines the props that are shared by default.
+	// It is called lazily when a page is rendered, so it is not called for the requests that do not render a page.
+	// It is called at most once per request, and it must not call Shared, which waits for it.
+	// If it returns an error, Render returns the error, and Shared logs the error and omits its props.
+	// The values of the props can be callbacks and props such as Optional,
+	// which are evaluated only when the props are sent in the same way as the page props.
 	// see https://inertiajs.com/shared-data
 	Share SharedDataFunc
+	// Defines the data of the root template that is shared by default, such as the page title and the locale.
+	// It is merged into RenderContext.ViewData, and the view data passed to RenderWithViewData takes precedence.
+	// It is called lazily when a page is rendered as HTML, so it is not called for the Inertia requests.
+	ShareView SharedDataFunc
 	// Renderer is a renderer that is used for rendering the root view.
 	Renderer Renderer
-	// ClearHistoryCookieKey is a key for the cookie that is used to clear the history state.
+	// ClearHistoryCookieKey was a key for the cookie that was used to clear the history state.
+	//
+	// Deprecated: The clear history flag is stored in the FlashStore. This option is ignored.
 	ClearHistoryCookieKey string
+	// FlashStore stores the state that survives until the next request,
+	// such as the clear history flag, validation errors and flash messages.
+	// If it is nil, a CookieFlashStore signed with FlashSecret is used.
+	FlashStore FlashStore
+	// FlashSecret is a key to sign the cookie of the default FlashStore.
+	// If it is empty, the INERTIA_FLASH_SECRET environment variable is used.
+	// It must be the same among all the instances of your application, so that a cookie signed by one instance is valid on the others.
+	// If neither FlashStore nor the secret is given, a random key is generated per process, and a warning is logged,
+	// because the cookie signed by one instance is invalid on the others, and after a restart.
+	FlashSecret []byte
+	// BackFallbackURL is the URL that Back redirects to when the previous page is unknown.
+	BackFallbackURL string
+	// DontFlash is a list of the input fields that are never stored as old input.
+	DontFlash []string
+	// MaxPropConcurrency is the maximum number of the props that are evaluated concurrently.
+	// The callbacks of the props, such as Optional, Defer and functions, run in parallel up to this number.
+	// If it is less than or equal to 1, the props are evaluated sequentially.
+	// It can be overridden per request by SetMaxPropConcurrency.
+	MaxPropConcurrency int
+	// OnPropError is called when a prop wrapped by Fallback fails, and the fallback value is used instead.
+	// It may be called concurrently when MaxPropConcurrency is set.
+	// If it is nil, the error is logged.
+	OnPropError PropErrorFunc
+	// PropsDecodeMode is the way to convert a struct passed to Render to the props.
+	// The default is PropsDecodeMapstructure. PropsDecodeJSON keeps the encoding/json semantics of the nested values.
+	PropsDecodeMode PropsDecodeMode
+	// SharedPropsMergeMode is the way to merge the shared props and the page props.
+	// The default is SharedPropsMergeShallow. SharedPropsMergeDeep merges the nested maps,
+	// so that the middleware and the handlers can contribute to the same namespace, like "auth".
+	SharedPropsMergeMode SharedPropsMergeMode
 	// IsSsrDisabled is a flag that determines whether server-side rendering is disabled.
 	// If this is true, server-side rendering is disabled even if the renderer supports and is configured for it.
 	IsSsrDisabled bool
@@ -35,14 +83,29 @@ type MiddlewareConfig struct {
 
 type SharedDataFunc func(c echo.Context) (map[string]any, error)
 
+type PropErrorFunc func(c echo.Context, err *PropError)
+
 var DefaultMiddlewareConfig = MiddlewareConfig{
-	Skipper:               middleware.DefaultSkipper,
-	RootView:              "app.html",
-	VersionFunc:           defaultVersionFunc(),
-	Share:                 nil,
-	Renderer:              nil,
-	ClearHistoryCookieKey: "inertia.clear_history",
-	IsSsrDisabled:         false,
+	Skipper:              middleware.DefaultSkipper,
+	RootView:             "app.html",
+	RootViewFunc:         nil,
+	VersionFunc:          defaultVersionFunc(),
+	Share:                nil,
+	ShareView:            nil,
+	Renderer:             nil,
+	FlashStore:           nil,
+	FlashSecret:          nil,
+	BackFallbackURL:      "/",
+	DontFlash:            []string{"password", "password_confirmation", "current_password"},
+	MaxPropConcurrency:   0,
+	OnPropError:          defaultOnPropError,
+	PropsDecodeMode:      PropsDecodeMapstructure,
+	SharedPropsMergeMode: SharedPropsMergeShallow,
+	IsSsrDisabled:        false,
+}
+
+func defaultOnPropError(c echo.Context, err *PropError) {
+	c.Logger().Error(err)
 }
 
 func defaultVersionFunc() VersionFunc {
@@ -63,6 +126,19 @@ func defaultVersionFunc() VersionFunc {
 	}
 }
 
+// processFlashSecret returns the key to sign the flash cookie when no secret is given.
+// It is generated randomly once per process, so that all the middleware in the process share it.
+var processFlashSecret = sync.OnceValue(func() []byte {
+	log.Print("inertia-echo: no secret is given to sign the flash cookie, so a random key is used. " +
+		"The cookie is invalid on the other instances and after a restart. " +
+		"Set MiddlewareConfig.FlashSecret or the INERTIA_FLASH_SECRET environment variable.")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+})
+
 // MiddlewareWithConfig returns an echo middleware that adds the Inertia instance to the context.
 func MiddlewareWithConfig(config MiddlewareConfig) echo.MiddlewareFunc {
 	// Defaults
@@ -75,8 +151,23 @@ func MiddlewareWithConfig(config MiddlewareConfig) echo.MiddlewareFunc {
 	if config.VersionFunc == nil {
 		config.VersionFunc = DefaultMiddlewareConfig.VersionFunc
 	}
-	if config.ClearHistoryCookieKey == "" {
-		config.ClearHistoryCookieKey = DefaultMiddlewareConfig.ClearHistoryCookieKey
+	if config.FlashStore == nil {
+		if len(config.FlashSecret) == 0 {
+			config.FlashSecret = []byte(os.Getenv("INERTIA_FLASH_SECRET"))
+		}
+		if len(config.FlashSecret) == 0 {
+			config.FlashSecret = processFlashSecret()
+		}
+		config.FlashStore = NewCookieFlashStore(config.FlashSecret)
+	}
+	if config.BackFallbackURL == "" {
+		config.BackFallbackURL = DefaultMiddlewareConfig.BackFallbackURL
+	}
+	if config.DontFlash == nil {
+		config.DontFlash = DefaultMiddlewareConfig.DontFlash
+	}
+	if config.OnPropError == nil {
+		config.OnPropError = DefaultMiddlewareConfig.OnPropError
 	}
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -85,45 +176,49 @@ func MiddlewareWithConfig(config MiddlewareConfig) echo.MiddlewareFunc {
 				return next(c)
 			}
 
-			var sharedProps map[string]any
-			if config.Share != nil {
-				ret, err := config.Share(c)
-				if err != nil {
-					return err
-				}
-				sharedProps = ret
-			} else {
-				sharedProps = map[string]any{}
-			}
-
 			// Create an Inertia instance.
 			i := &Inertia{
-				echoContext:           c,
-				rootView:              config.RootView,
-				sharedProps:           sharedProps,
-				version:               config.VersionFunc,
-				renderer:              config.Renderer,
-				clearHistoryCookieKey: config.ClearHistoryCookieKey,
-				isSsrDisabled:         config.IsSsrDisabled,
+				echoContext:          c,
+				rootView:             config.RootView,
+				rootViewFunc:         config.RootViewFunc,
+				sharedProps:          map[string]any{},
+				shareFunc:            config.Share,
+				shareViewFunc:        config.ShareView,
+				version:              config.VersionFunc,
+				renderer:             config.Renderer,
+				isSsrDisabled:        config.IsSsrDisabled,
+				flashStore:           config.FlashStore,
+				backFallbackURL:      config.BackFallbackURL,
+				dontFlash:            config.DontFlash,
+				maxPropConcurrency:   config.MaxPropConcurrency,
+				onPropError:          config.OnPropError,
+				propsDecodeMode:      config.PropsDecodeMode,
+				sharedPropsMergeMode: config.SharedPropsMergeMode,
 			}
 			c.Set(key, i)
 
 			req := c.Request()
 			res := c.Response()
 
+			if err = i.loadFlash(); err != nil {
+				return
+			}
+			// Store the state that has not been sent to the client yet.
+			// Typically, this happens when a handler redirects to another page.
+			res.Before(i.saveFlash)
+
 			i.partialComponent = req.Header.Get(HeaderXInertiaPartialComponent)
 			i.onlyProps = splitAndRemoveEmpty(req.Header.Get(HeaderXInertiaPartialData), ",")
 			i.exceptProps = splitAndRemoveEmpty(req.Header.Get(HeaderXInertiaPartialExcept), ",")
 			i.resetProps = splitAndRemoveEmpty(req.Header.Get(HeaderXInertiaReset), ",")
+			i.exceptOnceProps = splitAndRemoveEmpty(req.Header.Get(HeaderXInertiaExceptOnceProps), ",")
+			i.isPrefetch = isPrefetchRequest(req)
+			i.errorBag = req.Header.Get(HeaderXInertiaErrorBag)
+			i.mergeIntent = req.Header.Get(HeaderXInertiaInfiniteScrollMergeIntent)
 
 			if req.Header.Get(HeaderXInertia) == "" {
 				// Not inertial request
-				if err = next(c); err != nil {
-					return
-				}
-				i.sendClearHistoryCookieIfNeeded()
-
-				return
+				return next(c)
 			}
 
 			// In the event that the assets change, initiate a
@@ -147,7 +242,6 @@ func MiddlewareWithConfig(config MiddlewareConfig) echo.MiddlewareFunc {
 			if err = next(c); err != nil {
 				return
 			}
-			i.sendClearHistoryCookieIfNeeded()
 
 			changeRedirectCode(req, res)
 			return
@@ -167,11 +261,12 @@ func checkVersion(req *http.Request, version string) bool {
 
 // changeRedirectCode changes the status code during redirects, ensuring they are made as
 // GET requests, preventing "MethodNotAllowedHttpException" errors.
+// It also handles a spoofed method of a POST request (see MethodOverride).
 // see https://inertiajs.com/redirects
 func changeRedirectCode(req *http.Request, res *echo.Response) {
 	if req.Header.Get(HeaderXInertia) != "" &&
 		res.Status == 302 &&
-		inArray(req.Method, []string{"PUT", "PATCH", "DELETE"}) {
+		inArray(requestMethod(req), []string{"PUT", "PATCH", "DELETE"}) {
 		res.Status = 303
 		res.Writer.WriteHeader(303)
 	}
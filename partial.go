@@ -0,0 +1,212 @@
+package inertia
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Partial reloads with dot notation
+// The keys of the "only" and "except" props can be paths into nested props, like "user.permissions".
+// see https://inertiajs.com/partial-reloads
+
+// splitPropPath splits the key of a partial reload into the path of the prop.
+func splitPropPath(key string) []string {
+	return strings.Split(key, ".")
+}
+
+// resolvePropMap unwraps the value if it is a prop wrapper, and returns the unwrapped value and its map form.
+// The map is nil if the value is not a map or a struct.
+// The props nested in the value are not evaluated, so that only the props on the path are evaluated.
+func (e *propEvaluator) resolvePropMap(value any) (any, map[string]any, error) {
+	v, _, err := e.unwrap(value)
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, propMapOf(v), nil
+}
+
+var jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+
+// propMapOf returns the map form of the value for the paths of partial reloads.
+// The maps with string keys are converted to map[string]any,
+// and the structs are converted to the maps by the field names of encoding/json.
+// It returns nil for the other values and the values that implement json.Marshaler,
+// because their JSON does not have the same keys as their fields.
+func propMapOf(value any) map[string]any {
+	if m, ok := value.(map[string]any); ok {
+		return m
+	}
+
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Type().Implements(jsonMarshalerType) || reflect.PointerTo(v.Type()).Implements(jsonMarshalerType) {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		m := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = iter.Value().Interface()
+		}
+		return m
+	case reflect.Struct:
+		// The struct is copied to be addressable, so that the fields of the unexported embedded structs can be read.
+		addressable := reflect.New(v.Type()).Elem()
+		addressable.Set(v)
+		m := map[string]any{}
+		structPropMap(addressable, m)
+		return m
+	}
+	return nil
+}
+
+// structPropMap sets the fields of the struct to the map in the same way as encoding/json.
+// The fields of the embedded structs are promoted, and the fields of the outer struct take precedence.
+func structPropMap(v reflect.Value, m map[string]any) {
+	var embedded []reflect.Value
+	for n := 0; n < v.NumField(); n++ {
+		f := v.Type().Field(n)
+		name, options, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && options == "" {
+			continue
+		}
+		fv := v.Field(n)
+		if f.Anonymous && name == "" && indirectKind(f.Type) == reflect.Struct {
+			if !f.IsExported() {
+				fv = exposeField(fv)
+			}
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			embedded = append(embedded, fv)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if strings.Contains(","+options+",", ",omitempty,") && isEmptyValue(fv) {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		m[name] = fv.Interface()
+	}
+
+	for _, ev := range embedded {
+		promoted := map[string]any{}
+		structPropMap(ev, promoted)
+		for k, vv := range promoted {
+			if _, exists := m[k]; !exists {
+				m[k] = vv
+			}
+		}
+	}
+}
+
+// pickPropPaths returns the evaluated values at the paths, which are relative to the value.
+// An empty path selects the whole value. The selected values are returned in nested maps.
+// The paths are grouped by their first keys, so that each prop on the paths is unwrapped only once.
+// Only the props on the paths are evaluated.
+func (e *propEvaluator) pickPropPaths(value any, paths [][]string) (any, bool, error) {
+	for _, path := range paths {
+		if len(path) == 0 {
+			v, _, err := e.evaluate(value)
+			if err != nil {
+				return nil, false, err
+			}
+			return v, true, nil
+		}
+	}
+
+	_, m, err := e.resolvePropMap(value)
+	if err != nil || m == nil {
+		return nil, false, err
+	}
+	var keys []string
+	children := map[string][][]string{}
+	for _, path := range paths {
+		if _, ok := children[path[0]]; !ok {
+			keys = append(keys, path[0])
+		}
+		children[path[0]] = append(children[path[0]], path[1:])
+	}
+
+	var picked map[string]any
+	for _, key := range keys {
+		child, exists := m[key]
+		if !exists {
+			continue
+		}
+		e.path = append(e.path, key)
+		v, found, err := e.pickPropPaths(child, children[key])
+		e.path = e.path[:len(e.path)-1]
+		if err != nil {
+			return nil, false, err
+		}
+		if found {
+			if picked == nil {
+				picked = map[string]any{}
+			}
+			picked[key] = v
+		}
+	}
+	return picked, picked != nil, nil
+}
+
+// omitPropPath returns a copy of the value without the prop at the path.
+// The given value is not modified, because it may be shared between requests.
+func (e *propEvaluator) omitPropPath(value any, path []string) (any, error) {
+	v, m, err := e.resolvePropMap(value)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return v, nil
+	}
+	child, exists := m[path[0]]
+	if !exists {
+		return v, nil
+	}
+
+	newMap := make(map[string]any, len(m))
+	for k, vv := range m {
+		newMap[k] = vv
+	}
+	if len(path) == 1 {
+		delete(newMap, path[0])
+		return newMap, nil
+	}
+
+	e.path = append(e.path, path[0])
+	newChild, err := e.omitPropPath(child, path[1:])
+	if err != nil {
+		return nil, err
+	}
+	newMap[path[0]] = newChild
+	return newMap, nil
+}
+
+// hasSelectedDescendant reports whether a nested prop of the key is in the keys.
+func hasSelectedDescendant(key string, keys []string) bool {
+	for _, k := range keys {
+		if strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+	return false
+}
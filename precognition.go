@@ -0,0 +1,158 @@
+package inertia
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// Precognition is a protocol that allows the client to validate a form before submitting it.
+// See: https://laravel.com/docs/precognition
+
+const (
+	HeaderPrecognition             = "Precognition"
+	HeaderPrecognitionSuccess      = "Precognition-Success"
+	HeaderPrecognitionValidateOnly = "Precognition-Validate-Only"
+)
+
+// ValidatorFunc is the validation step of a handler.
+// It returns validation errors if the request is invalid.
+// The returned error is for unexpected failures and is returned to Echo as it is.
+type ValidatorFunc func(c echo.Context) (ValidationErrors, error)
+
+type PrecognitionConfig struct {
+	Skipper middleware.Skipper
+	// Validator is the validation step of the handler.
+	// It is required.
+	Validator ValidatorFunc
+}
+
+// Precognition returns a route middleware that runs the validation step of the handler.
+//
+// For a precognitive request, only the validator runs and the middleware responds with
+// 204 No Content and the Precognition-Success header on success, or 422 Unprocessable Entity with the errors on failure.
+// For a normal request, the middleware redirects back with the errors on failure (see BackWithErrors),
+// otherwise it calls the handler.
+// Both ways use the same error format as the "errors" prop.
+//
+//	e.POST("/users", createUser, inertia.Precognition(validateUser))
+//
+// The request body is buffered, so that both the validator and the handler can bind it.
+func Precognition(validator ValidatorFunc) echo.MiddlewareFunc {
+	return PrecognitionWithConfig(PrecognitionConfig{
+		Validator: validator,
+	})
+}
+
+func PrecognitionWithConfig(config PrecognitionConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	if config.Validator == nil {
+		panic("inertia-echo: precognition middleware requires a validator")
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			res := c.Response()
+			addVary(res.Header(), HeaderPrecognition)
+
+			var body []byte
+			if req.Body != nil {
+				b, err := io.ReadAll(req.Body)
+				if err != nil {
+					return err
+				}
+				body = b
+				req.Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			errs, err := config.Validator(c)
+			if err != nil {
+				return err
+			}
+
+			if IsPrecognitive(c) {
+				res.Header().Set(HeaderPrecognition, "true")
+
+				only := splitAndRemoveEmpty(req.Header.Get(HeaderPrecognitionValidateOnly), ",")
+				errs = filterValidationErrors(errs, only)
+				if len(errs) > 0 {
+					return c.JSON(http.StatusUnprocessableEntity, map[string]any{
+						"message": validationErrorsMessage(errs),
+						"errors":  errs,
+					})
+				}
+
+				res.Header().Set(HeaderPrecognitionSuccess, "true")
+				return c.NoContent(http.StatusNoContent)
+			}
+
+			if len(errs) > 0 {
+				i, err := Get(c)
+				if err != nil {
+					return err
+				}
+				return i.BackWithErrors(errs)
+			}
+
+			if body != nil {
+				req.Body = io.NopCloser(bytes.NewReader(body))
+			}
+			return next(c)
+		}
+	}
+}
+
+// IsPrecognitive reports whether the request is a precognitive request.
+func IsPrecognitive(c echo.Context) bool {
+	return c.Request().Header.Get(HeaderPrecognition) == "true"
+}
+
+// filterValidationErrors returns the errors of the given fields and their nested fields.
+// If no fields are given, it returns all the errors.
+func filterValidationErrors(errs ValidationErrors, fields []string) ValidationErrors {
+	if len(fields) == 0 {
+		return errs
+	}
+
+	ret := ValidationErrors{}
+	for k, v := range errs {
+		for _, field := range fields {
+			if k == field || strings.HasPrefix(k, field+".") {
+				ret[k] = v
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// validationErrorsMessage returns a summary message of the errors like "The name field is required. (and 1 more error)".
+func validationErrorsMessage(errs ValidationErrors) string {
+	keys := make([]string, 0, len(errs))
+	for k := range errs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	message := errs[keys[0]]
+	switch n := len(keys) - 1; {
+	case n == 1:
+		message += " (and 1 more error)"
+	case n > 1:
+		message += fmt.Sprintf(" (and %d more errors)", n)
+	}
+	return message
+}
@@ -0,0 +1,207 @@
+package inertia
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func testNewPrecognitionEcho(t *testing.T, called *bool) *echo.Echo {
+	t.Helper()
+
+	type Form struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+
+	validator := func(c echo.Context) (ValidationErrors, error) {
+		form := &Form{}
+		if err := c.Bind(form); err != nil {
+			return nil, err
+		}
+		errs := ValidationErrors{}
+		if form.Name == "" {
+			errs.Add("name", "The name field is required.")
+		}
+		if form.Email == "" {
+			errs.Add("email", "The email field is required.")
+		}
+		return errs, nil
+	}
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		VersionFunc: func() string { return "" },
+		FlashStore:  NewMemoryFlashStore(),
+	}))
+	e.POST("/users", func(c echo.Context) error {
+		*called = true
+		form := &Form{}
+		if err := c.Bind(form); err != nil {
+			return err
+		}
+		if form.Name != "John" {
+			t.Errorf("expected the handler to bind the body, got %v", form)
+		}
+		return c.Redirect(http.StatusFound, "/users")
+	}, Precognition(validator))
+	return e
+}
+
+func TestPrecognition(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		validateOnly   string
+		expectedStatus int
+		expectedErrors map[string]any
+	}{
+		{
+			name:           "success",
+			body:           `{"name":"John","email":"john@example.com"}`,
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "failure",
+			body:           `{"name":"John"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedErrors: map[string]any{"email": "The email field is required."},
+		},
+		{
+			name:           "validate only the valid field",
+			body:           `{"name":"John"}`,
+			validateOnly:   "name",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "validate only the invalid fields",
+			body:           `{}`,
+			validateOnly:   "name",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedErrors: map[string]any{"name": "The name field is required."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			e := testNewPrecognitionEcho(t, &called)
+
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(HeaderPrecognition, "true")
+			if tt.validateOnly != "" {
+				req.Header.Set(HeaderPrecognitionValidateOnly, tt.validateOnly)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if called {
+				t.Error("expected the handler not to be called")
+			}
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if rec.Header().Get(HeaderPrecognition) != "true" {
+				t.Errorf("expected the Precognition header")
+			}
+
+			if tt.expectedStatus == http.StatusNoContent {
+				if rec.Header().Get(HeaderPrecognitionSuccess) != "true" {
+					t.Errorf("expected the Precognition-Success header")
+				}
+				return
+			}
+
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if !testDeepEqual(t, body["errors"], tt.expectedErrors) {
+				t.Errorf("expected errors %v, got %v", tt.expectedErrors, body["errors"])
+			}
+		})
+	}
+}
+
+func TestPrecognition_NormalRequest(t *testing.T) {
+	t.Run("valid request calls the handler", func(t *testing.T) {
+		called := false
+		e := testNewPrecognitionEcho(t, &called)
+
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"John","email":"john@example.com"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if !called {
+			t.Error("expected the handler to be called")
+		}
+	})
+
+	t.Run("invalid request redirects back", func(t *testing.T) {
+		called := false
+		e := testNewPrecognitionEcho(t, &called)
+
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"John"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("Referer", "/users/create")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if called {
+			t.Error("expected the handler not to be called")
+		}
+		if loc := rec.Header().Get(echo.HeaderLocation); loc != "/users/create" {
+			t.Errorf("expected redirect to /users/create, got %q", loc)
+		}
+	})
+}
+
+func TestPrecognition_Vary(t *testing.T) {
+	validator := func(c echo.Context) (ValidationErrors, error) {
+		return nil, nil
+	}
+	e := echo.New()
+	// The middleware is applied on both the group and the route.
+	g := e.Group("", Precognition(validator))
+	g.POST("/users", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, Precognition(validator))
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{}`))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	count := 0
+	for _, v := range rec.Header().Values(echo.HeaderVary) {
+		for _, vv := range splitAndRemoveEmpty(v, ",") {
+			if vv == HeaderPrecognition {
+				count++
+			}
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected the Vary header to have Precognition once, got %v", rec.Header().Values(echo.HeaderVary))
+	}
+}
+
+func TestValidationErrorsMessage(t *testing.T) {
+	tests := []struct {
+		errs     ValidationErrors
+		expected string
+	}{
+		{ValidationErrors{"a": "A."}, "A."},
+		{ValidationErrors{"a": "A.", "b": "B."}, "A. (and 1 more error)"},
+		{ValidationErrors{"a": "A.", "b": "B.", "c": "C."}, "A. (and 2 more errors)"},
+	}
+	for _, tt := range tests {
+		if msg := validationErrorsMessage(tt.errs); msg != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, msg)
+		}
+	}
+}
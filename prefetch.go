@@ -0,0 +1,25 @@
+package inertia
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Prefetching
+// Inertia.js prefetches the pages of the links in the background, and the requests have the "Purpose: prefetch" header.
+// see https://inertiajs.com/prefetching
+
+const HeaderPurpose = "Purpose"
+
+// IsPrefetch reports whether the request is a prefetch request.
+// Handlers can use it to skip the side effects, such as view counters and audit logs,
+// because the prefetched page might never be visited.
+func IsPrefetch(c echo.Context) bool {
+	return isPrefetchRequest(c.Request())
+}
+
+func isPrefetchRequest(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get(HeaderPurpose), "prefetch")
+}
@@ -0,0 +1,92 @@
+package inertia
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestPrefetch(t *testing.T) {
+	tests := []struct {
+		name                 string
+		purpose              string
+		expectedPrefetch     bool
+		expectedProps        []string
+		expectedDeferred     []any
+		expectedCacheControl string
+	}{
+		{
+			name:             "normal visit",
+			expectedProps:    []string{"errors", "user", "stats"},
+			expectedDeferred: []any{"permissions"},
+		},
+		{
+			name:                 "prefetch",
+			purpose:              "prefetch",
+			expectedPrefetch:     true,
+			expectedProps:        []string{"errors", "user"},
+			expectedDeferred:     []any{"permissions", "stats"},
+			expectedCacheControl: "private",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(MiddlewareWithConfig(MiddlewareConfig{
+				Renderer:    testNewMockRenderer(t, func(ctx *RenderContext) error { return nil }),
+				VersionFunc: func() string { return "" },
+				FlashStore:  NewMemoryFlashStore(),
+			}))
+			e.GET("/", func(c echo.Context) error {
+				if IsPrefetch(c) != tt.expectedPrefetch || MustGet(c).IsPrefetch() != tt.expectedPrefetch {
+					t.Errorf("expected IsPrefetch to be %v", tt.expectedPrefetch)
+				}
+				return Render(c, "Home", map[string]any{
+					"user": "John",
+					"permissions": Defer(func() (any, error) {
+						return []string{"edit"}, nil
+					}),
+					"stats": Defer(func() (any, error) {
+						return 10, nil
+					}).OnlyOnPrefetch(),
+				})
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(HeaderXInertia, "true")
+			if tt.purpose != "" {
+				req.Header.Set(HeaderPurpose, tt.purpose)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			page := &Page{}
+			if err := json.Unmarshal(rec.Body.Bytes(), page); err != nil {
+				t.Fatal(err)
+			}
+			if len(page.Props) != len(tt.expectedProps) {
+				t.Errorf("expected props %v, got %v", tt.expectedProps, page.Props)
+			}
+			for _, key := range tt.expectedProps {
+				if _, ok := page.Props[key]; !ok {
+					t.Errorf("expected prop %s, got %v", key, page.Props)
+				}
+			}
+			deferred, _ := page.DeferredProps["default"].([]any)
+			if len(deferred) != len(tt.expectedDeferred) {
+				t.Errorf("expected deferred props %v, got %v", tt.expectedDeferred, deferred)
+			}
+
+			if vary := rec.Header().Values(echo.HeaderVary); !testDeepEqual(t, vary, []string{HeaderXInertia, HeaderPurpose}) {
+				t.Errorf("expected Vary to have X-Inertia and Purpose, got %v", vary)
+			}
+			if cc := rec.Header().Get(echo.HeaderCacheControl); cc != tt.expectedCacheControl {
+				t.Errorf("expected Cache-Control %q, got %q", tt.expectedCacheControl, cc)
+			}
+		})
+	}
+}
@@ -1,15 +1,91 @@
 package inertia
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
 // IgnoreFirstLoadProp represents a prop that should be ignored on first load
 type IgnoreFirstLoadProp interface {
 	IsIgnoreFirstLoad()
 }
 
+// isIgnoredOnFirstLoad reports whether the prop is ignored on first load.
+// A deferred prop with OnlyOnPrefetch is ignored only on prefetch requests.
+func isIgnoredOnFirstLoad(prop any, prefetch bool) bool {
+	prop = unwrapFallback(prop)
+	if deferProp, ok := prop.(*DeferProp); ok {
+		return deferProp.IsDeferred(prefetch)
+	}
+	_, ok := prop.(IgnoreFirstLoadProp)
+	return ok
+}
+
 // evaluateProps evaluates the given props and update it.
 // It is the same purpose as resolvePropertyInstances that is used in official inertia-laravel package.
 func evaluateProps(values map[string]any) error {
+	return (&propEvaluator{}).evaluateProps(values)
+}
+
+func evaluatePropValue(value any) (any, error) {
+	v, _, err := (&propEvaluator{}).evaluate(value)
+	return v, err
+}
+
+// propEvaluator evaluates props recursively.
+// The prop wrappers and the callbacks nested in maps, slices and struct fields are evaluated as well as the top-level ones.
+// The given values are not modified. The containers that have nested props are copied.
+type propEvaluator struct {
+	// firstLoad omits the nested props that are ignored on first load,
+	// and records the groups of the nested deferred props.
+	firstLoad bool
+	// prefetch is true if the request is a prefetch request.
+	prefetch bool
+	// deferredGroups maps the groups of the nested deferred props to the top-level keys that have them.
+	deferredGroups map[string][]string
+	// key is the top-level key of the prop being evaluated.
+	key string
+	// path is the key path of the prop being evaluated. It is used for PropError.
+	path []string
+	// component is the page component that is being rendered. It is used for PropError.
+	component string
+	// onPropError is called with the error of a FallbackProp.
+	// It may be called concurrently when the props are evaluated concurrently.
+	onPropError func(err *PropError)
+	// visiting is the set of the pointers being walked, to prevent infinite recursion.
+	visiting map[uintptr]bool
+	// concurrency is the maximum number of the top-level props that are evaluated concurrently.
+	// If it is less than or equal to 1, the props are evaluated sequentially.
+	concurrency int
+	// ctx is the context that is passed to the callbacks of the props.
+	ctx context.Context
+}
+
+func (e *propEvaluator) context() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
+
+func (e *propEvaluator) evaluateProps(values map[string]any) error {
+	if e.concurrency > 1 && len(values) > 1 {
+		return e.evaluatePropsConcurrently(values)
+	}
+
 	for k, v := range values {
-		vv, err := evaluatePropValue(v)
+		// Stop evaluating the props if the request has been canceled.
+		if err := e.context().Err(); err != nil {
+			return err
+		}
+		e.key = k
+		e.path = []string{k}
+		vv, _, err := e.evaluate(v)
 		if err != nil {
 			return err
 		}
@@ -18,39 +94,440 @@ func evaluateProps(values map[string]any) error {
 	return nil
 }
 
-func evaluatePropValue(value any) (any, error) {
-	switch v := value.(type) {
-	case *LazyProp:
-		vv, err := v.callback()
-		if err != nil {
-			return nil, err
+// evaluatePropsConcurrently evaluates the top-level props in parallel up to the concurrency.
+// It stops starting the evaluations on the first error, and returns the error.
+// The context of the running callbacks is canceled on the first error.
+// The values are updated only if all the props are evaluated successfully.
+func (e *propEvaluator) evaluatePropsConcurrently(values map[string]any) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	ctx, cancel := context.WithCancel(e.context())
+	defer cancel()
+	results := make(map[string]any, len(values))
+	sem := make(chan struct{}, e.concurrency)
+
+	for k, v := range values {
+		sem <- struct{}{}
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed || ctx.Err() != nil {
+			<-sem
+			break
 		}
-		return evaluatePropValue(vv)
-	case *OptionalProp:
-		vv, err := v.callback()
-		if err != nil {
-			return nil, err
+
+		wg.Add(1)
+		go func(k string, v any) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// Each goroutine has its own evaluator, because the evaluator has the state of the walk.
+			child := &propEvaluator{
+				firstLoad:   e.firstLoad,
+				prefetch:    e.prefetch,
+				key:         k,
+				path:        []string{k},
+				component:   e.component,
+				onPropError: e.onPropError,
+				ctx:         ctx,
+			}
+			vv, _, err := child.evaluate(v)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			results[k] = vv
+			for group, keys := range child.deferredGroups {
+				for _, key := range keys {
+					e.key = key
+					e.addDeferredGroup(group)
+				}
+			}
+		}(k, v)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		// The request has been canceled before all the props started.
+		return err
+	}
+	for k, v := range results {
+		values[k] = v
+	}
+	return nil
+}
+
+// evaluate evaluates the value and the props nested in it.
+// It reports whether the evaluated value is different from the given value.
+func (e *propEvaluator) evaluate(value any) (any, bool, error) {
+	v, unwrapped, err := e.unwrap(value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	rv, changed, err := e.walk(reflect.ValueOf(v))
+	if err != nil {
+		return nil, false, err
+	}
+	if changed {
+		return rv.Interface(), true, nil
+	}
+	return v, unwrapped, nil
+}
+
+// unwrap evaluates the prop wrappers and the callbacks until the value is not a prop.
+// The error of a callback is returned as a PropError.
+func (e *propEvaluator) unwrap(value any) (any, bool, error) {
+	unwrapped := false
+	kind := ""
+	for {
+		if kind == "" {
+			kind = propKind(value)
+		}
+
+		var err error
+		switch v := value.(type) {
+		case *LazyProp:
+			value, err = callCallback(v.callback)
+		case *OptionalProp:
+			value, err = v.call(e.context(), v.callback)
+		case *DeferProp:
+			value, err = v.call(e.context(), v.callback)
+		case *AlwaysProp:
+			value = v.value
+		case *MergeProp:
+			value = v.value
+		case *ScrollProp:
+			value = v.value
+		case *OnceProp:
+			value = v.value
+		case *FallbackProp:
+			value, err = e.evaluateFallback(v)
+		case func() (any, error):
+			value, err = callCallback(v)
+		case func(context.Context) (any, error):
+			value, err = callCallback(func() (any, error) { return v(e.context()) })
+		case ContextCallback:
+			value, err = callCallback(func() (any, error) { return v(e.context()) })
+		case func() any:
+			value, err = callCallback(func() (any, error) { return v(), nil })
+		default:
+			return value, unwrapped, nil
 		}
-		return evaluatePropValue(vv)
-	case *DeferProp:
-		vv, err := v.callback()
 		if err != nil {
-			return nil, err
+			return nil, false, e.propError(kind, err)
 		}
-		return evaluatePropValue(vv)
+		unwrapped = true
+	}
+}
+
+// evaluateFallback evaluates the prop of the FallbackProp.
+// If the evaluation fails, the error is reported to onPropError and the fallback value is returned.
+// The error caused by the canceled request is returned as it is, because the response is not sent anyway.
+func (e *propEvaluator) evaluateFallback(p *FallbackProp) (any, error) {
+	v, _, err := e.evaluate(p.prop)
+	if err == nil {
+		return v, nil
+	}
+	if ctxErr := e.context().Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+		return nil, err
+	}
+
+	var pe *PropError
+	if !errors.As(err, &pe) {
+		pe = e.propError("fallback", err).(*PropError)
+	}
+	if e.onPropError != nil {
+		e.onPropError(pe)
+	}
+	return p.value, nil
+}
+
+// propError wraps the error of the prop being evaluated into a PropError.
+// The error that is already a PropError is returned as it is, so that it points at the innermost prop.
+func (e *propEvaluator) propError(kind string, err error) error {
+	var pe *PropError
+	if errors.As(err, &pe) {
+		return err
+	}
+
+	pe = &PropError{
+		Path:      strings.Join(e.path, "."),
+		Kind:      kind,
+		Component: e.component,
+		Err:       err,
+	}
+	var panicErr *panicError
+	if errors.As(err, &panicErr) {
+		pe.Stack = panicErr.stack
+	}
+	return pe
+}
+
+// propKind returns the kind of the prop for PropError.
+func propKind(value any) string {
+	switch value.(type) {
+	case *LazyProp:
+		return "lazy"
+	case *OptionalProp:
+		return "optional"
+	case *DeferProp:
+		return "defer"
 	case *AlwaysProp:
-		return evaluatePropValue(v.value)
+		return "always"
 	case *MergeProp:
-		return evaluatePropValue(v.value)
-	case func() (any, error):
-		vv, err := v()
-		if err != nil {
-			return nil, err
-		}
-		return evaluatePropValue(vv)
-	case func() any:
-		return evaluatePropValue(v())
+		return "merge"
+	case *ScrollProp:
+		return "scroll"
+	case *OnceProp:
+		return "once"
+	case *FallbackProp:
+		return "fallback"
 	default:
-		return value, nil
+		return "callback"
+	}
+}
+
+// walk evaluates the props nested in the value.
+// It returns a copy of the value if any nested prop is evaluated.
+func (e *propEvaluator) walk(v reflect.Value) (reflect.Value, bool, error) {
+	if !v.IsValid() || !canContainProps(v.Type()) {
+		return v, false, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false, nil
+		}
+		vv, changed, err := e.evaluate(v.Elem().Interface())
+		if err != nil || !changed {
+			return v, false, err
+		}
+		return valueOf(vv, v.Type()), true, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v, false, nil
+		}
+		// The map is copied only when the first element is changed, because most of the data has no props.
+		var newMap reflect.Value
+		// The key and the value are reused to avoid the allocations of MapIter.Key and MapIter.Value.
+		key := reflect.New(v.Type().Key()).Elem()
+		value := reflect.New(v.Type().Elem()).Elem()
+		iter := v.MapRange()
+		for iter.Next() {
+			key.SetIterKey(iter)
+			value.SetIterValue(iter)
+			vv, omit, changed, err := e.walkElem(mapKeyName(key), value)
+			if err != nil {
+				return v, false, err
+			}
+			if !changed {
+				continue
+			}
+			if !newMap.IsValid() {
+				newMap = reflect.MakeMapWithSize(v.Type(), v.Len())
+				copyIter := v.MapRange()
+				for copyIter.Next() {
+					newMap.SetMapIndex(copyIter.Key(), copyIter.Value())
+				}
+			}
+			if omit {
+				newMap.SetMapIndex(key, reflect.Value{})
+			} else {
+				newMap.SetMapIndex(key, vv)
+			}
+		}
+		if !newMap.IsValid() {
+			return v, false, nil
+		}
+		return newMap, true, nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return v, false, nil
+		}
+		// The slice is copied only when the first element is changed, because most of the data has no props.
+		var newSlice reflect.Value
+		for n := 0; n < v.Len(); n++ {
+			vv, omit, changed, err := e.walkElem(strconv.Itoa(n), v.Index(n))
+			if err != nil {
+				return v, false, err
+			}
+			if !changed {
+				continue
+			}
+			if !newSlice.IsValid() {
+				if v.Kind() == reflect.Slice {
+					newSlice = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+					reflect.Copy(newSlice, v)
+				} else {
+					newSlice = reflect.New(v.Type()).Elem()
+					newSlice.Set(v)
+				}
+			}
+			// The omitted element is replaced with the zero value to keep the indexes.
+			if omit {
+				newSlice.Index(n).SetZero()
+			} else {
+				newSlice.Index(n).Set(vv)
+			}
+		}
+		if !newSlice.IsValid() {
+			return v, false, nil
+		}
+		return newSlice, true, nil
+
+	case reflect.Pointer:
+		if v.IsNil() || e.visiting[v.Pointer()] {
+			return v, false, nil
+		}
+		if e.visiting == nil {
+			e.visiting = make(map[uintptr]bool)
+		}
+		e.visiting[v.Pointer()] = true
+		defer delete(e.visiting, v.Pointer())
+
+		vv, changed, err := e.walk(v.Elem())
+		if err != nil || !changed {
+			return v, false, err
+		}
+		newPtr := reflect.New(v.Type().Elem())
+		newPtr.Elem().Set(vv)
+		return newPtr, true, nil
+
+	case reflect.Struct:
+		newStruct := reflect.New(v.Type()).Elem()
+		newStruct.Set(v)
+		changed := false
+		for n := 0; n < v.NumField(); n++ {
+			f := v.Type().Field(n)
+			if !isWalkableField(f) {
+				continue
+			}
+			vv, omit, c, err := e.walkElem(fieldName(f), v.Field(n))
+			if err != nil {
+				return v, false, err
+			}
+			if !c {
+				continue
+			}
+			changed = true
+			if omit {
+				newStruct.Field(n).SetZero()
+			} else {
+				newStruct.Field(n).Set(vv)
+			}
+		}
+		if !changed {
+			return v, false, nil
+		}
+		return newStruct, true, nil
+	}
+
+	return v, false, nil
+}
+
+// walkElem walks an element of a container.
+// On first load, it reports that the element should be omitted if it is a prop that is ignored on first load.
+func (e *propEvaluator) walkElem(name string, v reflect.Value) (reflect.Value, bool, bool, error) {
+	e.path = append(e.path, name)
+	defer func() { e.path = e.path[:len(e.path)-1] }()
+
+	if e.firstLoad && v.Kind() == reflect.Interface && !v.IsNil() {
+		if p := v.Elem().Interface(); isIgnoredOnFirstLoad(p, e.prefetch) {
+			if deferProp, ok := unwrapFallback(p).(*DeferProp); ok {
+				e.addDeferredGroup(deferProp.Group())
+			}
+			return v, true, true, nil
+		}
+	}
+
+	vv, changed, err := e.walk(v)
+	return vv, false, changed, err
+}
+
+func (e *propEvaluator) addDeferredGroup(group string) {
+	if e.deferredGroups == nil {
+		e.deferredGroups = make(map[string][]string)
+	}
+	if !inArray(e.key, e.deferredGroups[group]) {
+		e.deferredGroups[group] = append(e.deferredGroups[group], e.key)
+	}
+}
+
+// mapKeyName returns the name of the map key for the path of PropError.
+func mapKeyName(k reflect.Value) string {
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+	return fmt.Sprint(k.Interface())
+}
+
+// valueOf returns the reflect.Value of x that can be set to a value of type t.
+func valueOf(x any, t reflect.Type) reflect.Value {
+	if x == nil {
+		return reflect.Zero(t)
+	}
+	return reflect.ValueOf(x)
+}
+
+func isWalkableField(f reflect.StructField) bool {
+	return f.IsExported() && fieldName(f) != "-"
+}
+
+// fieldName returns the name of the struct field in JSON.
+func fieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+var canContainPropsCache sync.Map
+
+// canContainProps reports whether a value of the type can have nested props.
+// Only the interface values can have props, so the types that do not have any interface are skipped without walking.
+func canContainProps(t reflect.Type) bool {
+	if v, ok := canContainPropsCache.Load(t); ok {
+		return v.(bool)
+	}
+	ret := canContainPropsType(t, make(map[reflect.Type]bool))
+	canContainPropsCache.Store(t, ret)
+	return ret
+}
+
+func canContainPropsType(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Pointer:
+		return canContainPropsType(t.Elem(), visiting)
+	case reflect.Struct:
+		if visiting[t] {
+			return false
+		}
+		visiting[t] = true
+		for n := 0; n < t.NumField(); n++ {
+			f := t.Field(n)
+			if isWalkableField(f) && canContainPropsType(f.Type, visiting) {
+				return true
+			}
+		}
 	}
+	return false
 }
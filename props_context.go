@@ -0,0 +1,72 @@
+package inertia
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+)
+
+// ContextCallback is a callback of a prop that receives the context of the request.
+// The context is canceled when the client aborts the request.
+type ContextCallback func(ctx context.Context) (any, error)
+
+// withoutContext converts a callback that does not receive the context to a ContextCallback.
+func withoutContext(callback func() (any, error)) ContextCallback {
+	return func(ctx context.Context) (any, error) {
+		return callback()
+	}
+}
+
+// propTimeout is embedded in the props that have a callback to limit the time of the callback.
+type propTimeout struct {
+	timeout  time.Duration
+	fallback any
+}
+
+// call calls the callback with the context.
+// If the callback does not finish within the timeout, it returns the fallback value instead of waiting for the callback.
+// The context of the callback is canceled at the timeout, so a context-aware callback can stop its work.
+// The callback that ignores the context is abandoned, and it keeps running in its goroutine until it returns.
+func (t *propTimeout) call(ctx context.Context, callback ContextCallback) (any, error) {
+	if t.timeout <= 0 {
+		return callCallback(func() (any, error) { return callback(ctx) })
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	type result struct {
+		value any
+		err   error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		v, err := callCallback(func() (any, error) { return callback(timeoutCtx) })
+		ch <- result{value: v, err: err}
+	}()
+
+	select {
+	case r := <-ch:
+		if r.err != nil && ctx.Err() == nil && timeoutCtx.Err() == context.DeadlineExceeded {
+			// The callback has returned the error of the timeout.
+			return t.fallback, nil
+		}
+		return r.value, r.err
+	case <-timeoutCtx.Done():
+		if err := ctx.Err(); err != nil {
+			// The request has been canceled.
+			return nil, err
+		}
+		return t.fallback, nil
+	}
+}
+
+// callCallback calls the callback of a prop, and recovers a panic into an error.
+func callCallback(callback func() (any, error)) (v any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &panicError{value: r, stack: debug.Stack()}
+		}
+	}()
+	return callback()
+}
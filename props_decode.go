@@ -0,0 +1,162 @@
+package inertia
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// PropsDecodeMode is the way to convert a struct to the props.
+type PropsDecodeMode int
+
+const (
+	// PropsDecodeMapstructure decodes a struct with mapstructure.
+	// The nested structs are converted to maps, so their json tags and MarshalJSON methods are not used.
+	PropsDecodeMapstructure PropsDecodeMode = iota
+	// PropsDecodeJSON reads only the top-level fields of a struct with the prop tags.
+	// The nested values are kept as they are, so they are encoded with the encoding/json semantics,
+	// such as json tags, MarshalJSON methods and the format of time.Time.
+	// The exported fields of the embedded structs are promoted like encoding/json.
+	// The values other than structs, such as map[string]string, are decoded in the same way as PropsDecodeMapstructure.
+	PropsDecodeJSON
+)
+
+// decodeProps converts the props data to the props.
+// The props data that is nil is treated as empty props.
+func decodeProps(propsData any, mode PropsDecodeMode) (map[string]any, error) {
+	if props, ok := propsData.(map[string]any); ok {
+		return props, nil
+	}
+	if propsData == nil {
+		return map[string]any{}, nil
+	}
+
+	// The values other than structs, such as map[string]string, are decoded with mapstructure in both modes.
+	if mode == PropsDecodeJSON && indirectKind(reflect.TypeOf(propsData)) == reflect.Struct {
+		props := make(map[string]any)
+		if err := decodeStructProps(propsData, props); err != nil {
+			return nil, fmt.Errorf("failed to decode propsData: %w", err)
+		}
+		return props, nil
+	}
+
+	var props map[string]any
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName: "prop",
+		Result:  &props,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(propsData); err != nil {
+		return nil, fmt.Errorf("failed to decode propsData: %w", err)
+	}
+	if props == nil {
+		return map[string]any{}, nil
+	}
+	if err := applyPropTags(propsData, props); err != nil {
+		return nil, err
+	}
+	return props, nil
+}
+
+// decodeStructProps sets the top-level fields of the struct to the props without converting the values.
+// The embedded structs that have the squash option or no tag are flattened like encoding/json.
+// The props are wrapped with the props that the tag options declare.
+func decodeStructProps(propsData any, props map[string]any) error {
+	v := reflect.ValueOf(propsData)
+	if v.Kind() == reflect.Struct {
+		// The struct is copied to be addressable, so that the fields of the unexported embedded structs can be read.
+		nv := reflect.New(v.Type()).Elem()
+		nv.Set(v)
+		v = nv
+	}
+	return decodeStructValue(v, props)
+}
+
+func decodeStructValue(v reflect.Value, props map[string]any) error {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	t := v.Type()
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		embedded := f.Anonymous && indirectKind(f.Type) == reflect.Struct
+		if !f.IsExported() && !embedded {
+			continue
+		}
+		tag, err := parseFieldPropTag(f)
+		if err != nil {
+			return fmt.Errorf("invalid prop tag of the field %s: %w", f.Name, err)
+		}
+		if tag.name == "-" {
+			continue
+		}
+
+		fv := v.Field(n)
+		if !f.IsExported() {
+			// The exported fields of an unexported embedded struct are promoted like encoding/json.
+			if err := decodeStructValue(exposeField(fv), props); err != nil {
+				return err
+			}
+			continue
+		}
+		if tag.squash || (embedded && tag.name == "") {
+			if err := decodeStructValue(fv, props); err != nil {
+				return err
+			}
+			continue
+		}
+		if tag.omitempty && isEmptyValue(fv) {
+			continue
+		}
+
+		name := tag.name
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = tag.wrap(fv.Interface())
+	}
+	return nil
+}
+
+// exposeField returns the value of the unexported embedded struct field that can be read with Interface.
+func exposeField(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return v
+		}
+		return reflect.NewAt(v.Type().Elem(), v.UnsafePointer())
+	}
+	return reflect.NewAt(v.Type(), v.Addr().UnsafePointer()).Elem()
+}
+
+// isEmptyValue reports whether the value is empty in the same way as the omitempty option of encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+func indirectKind(t reflect.Type) reflect.Kind {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem().Kind()
+	}
+	return t.Kind()
+}
@@ -1,11 +1,19 @@
 package inertia
 
+import (
+	"context"
+	"time"
+)
+
 type DeferProp struct {
-	callback  func() (any, error)
-	group     string
-	merge     bool
-	deepMerge bool
-	matchesOn []string
+	mergePaths
+	propTimeout
+	callback       ContextCallback
+	group          string
+	onlyOnPrefetch bool
+	merge          bool
+	deepMerge      bool
+	matchesOn      []string
 }
 
 func (p *DeferProp) IsIgnoreFirstLoad() {}
@@ -39,14 +47,64 @@ func (p *DeferProp) MatchOn(fields ...string) *DeferProp {
 	return p
 }
 
+// Append merges the value by appending it at the given paths inside the prop.
+// Without paths, the value is appended at the root.
+// see MergeProp.Append
+func (p *DeferProp) Append(paths ...string) *DeferProp {
+	p.merge = true
+	p.append(paths)
+	return p
+}
+
+// Prepend merges the value by prepending it at the given paths inside the prop.
+// Without paths, the value is prepended at the root.
+// see MergeProp.Prepend
+func (p *DeferProp) Prepend(paths ...string) *DeferProp {
+	p.merge = true
+	p.prepend(paths)
+	return p
+}
+
+// OnlyOnPrefetch defers the prop only on prefetch requests.
+// On the other visits, the prop is evaluated with the page, so that the prefetch requests stay cheap
+// without making the real visits load the prop after the page renders.
+// see https://inertiajs.com/prefetching
+func (p *DeferProp) OnlyOnPrefetch() *DeferProp {
+	p.onlyOnPrefetch = true
+	return p
+}
+
+// IsDeferred reports whether the prop is deferred in the request.
+func (p *DeferProp) IsDeferred(prefetch bool) bool {
+	return !p.onlyOnPrefetch || prefetch
+}
+
+// Timeout sets the time limit of the callback.
+// If the callback does not finish in time, the fallback value is used instead of failing the request.
+// The callback is not waited for after the timeout, and it keeps running in the background until it returns.
+// So the callback must stop when its context is canceled, and it must not use the echo.Context after the timeout,
+// because the echo.Context is reused by another request after the response is written.
+func (p *DeferProp) Timeout(d time.Duration, fallback any) *DeferProp {
+	p.timeout = d
+	p.fallback = fallback
+	return p
+}
+
 func Defer(callback func() (any, error)) *DeferProp {
-	return &DeferProp{
-		callback: callback,
-		group:    "default",
-	}
+	return DeferCtxWithGroup(withoutContext(callback), "default")
 }
 
 func DeferWithGroup(callback func() (any, error), group string) *DeferProp {
+	return DeferCtxWithGroup(withoutContext(callback), group)
+}
+
+// DeferCtx is the same as Defer, but the callback receives the context of the request.
+// The context is canceled when the client aborts the request.
+func DeferCtx(callback func(ctx context.Context) (any, error)) *DeferProp {
+	return DeferCtxWithGroup(callback, "default")
+}
+
+func DeferCtxWithGroup(callback func(ctx context.Context) (any, error), group string) *DeferProp {
 	return &DeferProp{
 		callback: callback,
 		group:    group,
@@ -0,0 +1,40 @@
+package inertia
+
+// FallbackProp is a prop that is replaced with the fallback value when its evaluation fails.
+// It is for the non-essential props, such as recommendations and counters,
+// so that an error of them does not fail the whole page.
+type FallbackProp struct {
+	prop  any
+	value any
+}
+
+func (p *FallbackProp) Prop() any {
+	return p.prop
+}
+
+// Fallback creates a FallbackProp.
+// The prop can be any prop, such as Defer, Optional and a function.
+// When the prop returns an error or panics, the error is reported to MiddlewareConfig.OnPropError,
+// and the value is used instead.
+// The error caused by the canceled request, such as the client disconnection, is not handled,
+// and the rendering fails with it.
+//
+//	inertia.Fallback(inertia.Defer(loadRecommendations), []Product{})
+func Fallback(prop any, value any) *FallbackProp {
+	return &FallbackProp{
+		prop:  prop,
+		value: value,
+	}
+}
+
+// unwrapFallback returns the prop wrapped by Fallback.
+// It is used to resolve the kind of the prop, such as deferred props and merge props.
+func unwrapFallback(value any) any {
+	for {
+		p, ok := value.(*FallbackProp)
+		if !ok {
+			return value
+		}
+		value = p.prop
+	}
+}
@@ -0,0 +1,64 @@
+package inertia
+
+import "context"
+
+// Typed prop constructors
+// They are the same as the untyped ones, but the callbacks are type-checked.
+// They return the same prop types, so the props satisfy Mergeable and IgnoreFirstLoadProp as well.
+//
+//	"users": inertia.DeferOf(func(ctx context.Context) ([]User, error) {
+//		return repo.ListUsers(ctx)
+//	}),
+
+// typedCallback converts a typed callback to a ContextCallback.
+func typedCallback[T any](callback func(ctx context.Context) (T, error)) ContextCallback {
+	return func(ctx context.Context) (any, error) {
+		v, err := callback(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// DeferOf is the typed version of DeferCtx.
+func DeferOf[T any](callback func(ctx context.Context) (T, error)) *DeferProp {
+	return DeferCtx(typedCallback(callback))
+}
+
+// DeferOfWithGroup is the typed version of DeferCtxWithGroup.
+func DeferOfWithGroup[T any](callback func(ctx context.Context) (T, error), group string) *DeferProp {
+	return DeferCtxWithGroup(typedCallback(callback), group)
+}
+
+// OptionalOf is the typed version of OptionalCtx.
+func OptionalOf[T any](callback func(ctx context.Context) (T, error)) *OptionalProp {
+	return OptionalCtx(typedCallback(callback))
+}
+
+// OnceOf is the typed version of Once with a callback.
+func OnceOf[T any](callback func(ctx context.Context) (T, error)) *OnceProp {
+	return Once(typedCallback(callback))
+}
+
+// LazyOf is the typed version of Lazy.
+func LazyOf[T any](callback func() (T, error)) *LazyProp {
+	return Lazy(func() (any, error) {
+		v, err := callback()
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	})
+}
+
+// AlwaysOf is the typed version of Always with a callback.
+// The callback is called on every request, including partial reloads that do not request the prop.
+func AlwaysOf[T any](callback func(ctx context.Context) (T, error)) *AlwaysProp {
+	return Always(typedCallback(callback))
+}
+
+// MergeOf is the typed version of Merge with a callback.
+func MergeOf[T any](callback func(ctx context.Context) (T, error)) *MergeProp {
+	return Merge(typedCallback(callback))
+}
@@ -7,7 +7,60 @@ type Mergeable interface {
 	MatchesOn() []string
 }
 
+// PathMergeable represents a mergeable prop that is appended or prepended at the root or at paths inside the prop.
+// For example, a prop that appends at the "data" path is sent as "key.data" in the mergeProps of the page object.
+type PathMergeable interface {
+	AppendsAtRoot() bool
+	PrependsAtRoot() bool
+	AppendsAtPaths() []string
+	PrependsAtPaths() []string
+}
+
+// mergePaths implements PathMergeable.
+// By default, a prop is appended at the root.
+type mergePaths struct {
+	appendRoot   bool
+	prependRoot  bool
+	appendPaths  []string
+	prependPaths []string
+}
+
+func (m *mergePaths) AppendsAtRoot() bool {
+	return m.appendRoot || (!m.prependRoot && len(m.appendPaths) == 0 && len(m.prependPaths) == 0)
+}
+
+func (m *mergePaths) PrependsAtRoot() bool {
+	return m.prependRoot
+}
+
+func (m *mergePaths) AppendsAtPaths() []string {
+	return m.appendPaths
+}
+
+func (m *mergePaths) PrependsAtPaths() []string {
+	return m.prependPaths
+}
+
+func (m *mergePaths) append(paths []string) {
+	if len(paths) == 0 {
+		m.appendRoot = true
+		m.prependRoot = false
+		return
+	}
+	m.appendPaths = append(m.appendPaths, paths...)
+}
+
+func (m *mergePaths) prepend(paths []string) {
+	if len(paths) == 0 {
+		m.prependRoot = true
+		m.appendRoot = false
+		return
+	}
+	m.prependPaths = append(m.prependPaths, paths...)
+}
+
 type MergeProp struct {
+	mergePaths
 	value     any
 	deepMerge bool
 	matchesOn []string
@@ -34,6 +87,22 @@ func (p *MergeProp) MatchOn(fields ...string) *MergeProp {
 	return p
 }
 
+// Append appends the value at the given paths inside the prop, e.g. "data.items".
+// The other parts of the prop are replaced.
+// Without paths, the value is appended at the root, which is the default.
+func (p *MergeProp) Append(paths ...string) *MergeProp {
+	p.append(paths)
+	return p
+}
+
+// Prepend prepends the value at the given paths inside the prop, e.g. "data.items".
+// The other parts of the prop are replaced.
+// Without paths, the value is prepended at the root.
+func (p *MergeProp) Prepend(paths ...string) *MergeProp {
+	p.prepend(paths)
+	return p
+}
+
 func Merge(value any) *MergeProp {
 	return &MergeProp{
 		value:     value,
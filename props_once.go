@@ -0,0 +1,106 @@
+package inertia
+
+import "time"
+
+// OnceMetadata is the metadata of a OnceProp.
+// It is sent as the onceProps of the page object.
+// see https://inertiajs.com/once-props
+type OnceMetadata struct {
+	// Prop is the key of the prop in the props.
+	Prop string `json:"prop"`
+	// ExpiresAt is the expiration time in milliseconds since the Unix epoch. nil means that it never expires.
+	ExpiresAt *int64 `json:"expiresAt"`
+}
+
+// OnceProp is a prop that the client remembers across visits.
+// Once the client has the prop, it is not sent again until it expires,
+// because the client lists it in the X-Inertia-Except-Once-Props header.
+type OnceProp struct {
+	value     any
+	key       string
+	expiresAt time.Time
+	expiresIn time.Duration
+	fresh     bool
+}
+
+// As sets the key that the client remembers the prop under.
+// By default, it is the key of the prop. A custom key can share the prop between pages.
+func (p *OnceProp) As(key string) *OnceProp {
+	p.key = key
+	return p
+}
+
+// Until sets the time when the prop expires.
+func (p *OnceProp) Until(t time.Time) *OnceProp {
+	p.expiresAt = t
+	p.expiresIn = 0
+	return p
+}
+
+// ExpiresIn sets the duration until the prop expires.
+// The expiration time is computed when the page is rendered, so the prop can be created once and reused.
+func (p *OnceProp) ExpiresIn(d time.Duration) *OnceProp {
+	p.expiresAt = time.Time{}
+	p.expiresIn = d
+	return p
+}
+
+// Fresh sends the prop even if the client already has it.
+func (p *OnceProp) Fresh() *OnceProp {
+	p.fresh = true
+	return p
+}
+
+// Key returns the key that the client remembers the prop under.
+// If it is empty, the key of the prop is used.
+func (p *OnceProp) Key() string {
+	return p.key
+}
+
+// ExpiresAt returns the time when the prop expires. The zero time means that it never expires.
+// If the duration is set by ExpiresIn, it returns the time that the duration is added to the current time.
+func (p *OnceProp) ExpiresAt() time.Time {
+	return p.expiresAtFrom(time.Now())
+}
+
+// expiresAtFrom returns the time when the prop expires, if it is rendered at the given time.
+func (p *OnceProp) expiresAtFrom(now time.Time) time.Time {
+	if p.expiresIn != 0 {
+		return now.Add(p.expiresIn)
+	}
+	return p.expiresAt
+}
+
+func (p *OnceProp) IsFresh() bool {
+	return p.fresh
+}
+
+// Once creates a OnceProp.
+// The value can be a callback, which is evaluated only when the prop is sent.
+//
+//	inertia.Once(func() (any, error) {
+//		return loadTranslations()
+//	}).ExpiresIn(24 * time.Hour)
+func Once(value any) *OnceProp {
+	return &OnceProp{
+		value: value,
+	}
+}
+
+// metadata returns the metadata of the prop that has the given key, if it is rendered at the given time.
+func (p *OnceProp) metadata(prop string, now time.Time) OnceMetadata {
+	m := OnceMetadata{Prop: prop}
+	if expiresAt := p.expiresAtFrom(now); !expiresAt.IsZero() {
+		ms := expiresAt.UnixMilli()
+		m.ExpiresAt = &ms
+	}
+	return m
+}
+
+// onceKey returns the key that the client remembers the prop under.
+func (p *OnceProp) onceKey(prop string) string {
+	if p.key != "" {
+		return p.key
+	}
+	return prop
+}
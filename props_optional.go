@@ -1,12 +1,34 @@
 package inertia
 
+import (
+	"context"
+	"time"
+)
+
 type OptionalProp struct {
-	callback func() (any, error)
+	propTimeout
+	callback ContextCallback
 }
 
 func (p *OptionalProp) IsIgnoreFirstLoad() {}
 
+// Timeout sets the time limit of the callback.
+// If the callback does not finish in time, the fallback value is used instead of failing the page.
+// The callback is not waited for after the timeout, and it keeps running in the background until it returns.
+// So the callback must stop when its context is canceled, and it must not use the echo.Context after the timeout,
+// because the echo.Context is reused by another request after the response is written.
+func (p *OptionalProp) Timeout(d time.Duration, fallback any) *OptionalProp {
+	p.timeout = d
+	p.fallback = fallback
+	return p
+}
+
 func Optional(callback func() (any, error)) *OptionalProp {
+	return OptionalCtx(withoutContext(callback))
+}
+
+// OptionalCtx is the same as Optional, but the callback receives the context of the request.
+func OptionalCtx(callback func(ctx context.Context) (any, error)) *OptionalProp {
 	return &OptionalProp{
 		callback: callback,
 	}
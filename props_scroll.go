@@ -0,0 +1,107 @@
+package inertia
+
+// ScrollMetadata is the pagination metadata of a ScrollProp.
+// It is sent as the scrollProps of the page object.
+// The page cursors can be page numbers or cursor strings, and nil means that there is no page.
+// see https://inertiajs.com/infinite-scroll
+type ScrollMetadata struct {
+	PageName     string `json:"pageName"`
+	PreviousPage any    `json:"previousPage"`
+	NextPage     any    `json:"nextPage"`
+	CurrentPage  any    `json:"currentPage"`
+	Reset        bool   `json:"reset"`
+}
+
+// ScrollProp is a prop for infinite scrolling.
+// Each page of the items is merged into the items on the client.
+// The items are appended by default, and prepended when the client loads the previous page.
+// The direction is decided by the client, so ScrollProp does not have the Append and Prepend methods of MergeProp.
+type ScrollProp struct {
+	value     any
+	metadata  ScrollMetadata
+	wrapper   string
+	matchesOn []string
+}
+
+func (p *ScrollProp) ShouldMerge() bool {
+	return true
+}
+
+func (p *ScrollProp) ShouldDeepMerge() bool {
+	return false
+}
+
+func (p *ScrollProp) MatchesOn() []string {
+	return p.matchesOn
+}
+
+func (p *ScrollProp) AppendsAtRoot() bool {
+	return p.mergePathsFor("").AppendsAtRoot()
+}
+
+func (p *ScrollProp) PrependsAtRoot() bool {
+	return p.mergePathsFor("").PrependsAtRoot()
+}
+
+func (p *ScrollProp) AppendsAtPaths() []string {
+	return p.mergePathsFor("").AppendsAtPaths()
+}
+
+func (p *ScrollProp) PrependsAtPaths() []string {
+	return p.mergePathsFor("").PrependsAtPaths()
+}
+
+// Wrapper sets the path of the items inside the prop. The default is "data".
+// An empty path means that the prop itself is the items.
+func (p *ScrollProp) Wrapper(path string) *ScrollProp {
+	p.wrapper = path
+	return p
+}
+
+func (p *ScrollProp) MatchOn(fields ...string) *ScrollProp {
+	p.matchesOn = append(p.matchesOn, fields...)
+	return p
+}
+
+func (p *ScrollProp) Metadata() ScrollMetadata {
+	return p.metadata
+}
+
+// mergePathsFor returns whether the items are appended or prepended
+// by the X-Inertia-Infinite-Scroll-Merge-Intent header.
+// The prop is not modified, because it may be shared between requests.
+func (p *ScrollProp) mergePathsFor(intent string) *mergePaths {
+	var paths []string
+	if p.wrapper != "" {
+		paths = []string{p.wrapper}
+	}
+
+	m := &mergePaths{}
+	if intent == "prepend" {
+		m.prepend(paths)
+	} else {
+		m.append(paths)
+	}
+	return m
+}
+
+// Scroll creates a ScrollProp.
+// The value is typically a map or a struct that has the items of the current page under the "data" key.
+//
+//	inertia.Scroll(map[string]any{"data": users}, inertia.ScrollMetadata{
+//		CurrentPage: 2,
+//		PreviousPage: 1,
+//		NextPage: 3,
+//	})
+func Scroll(value any, metadata ScrollMetadata) *ScrollProp {
+	if metadata.PageName == "" {
+		metadata.PageName = "page"
+	}
+
+	return &ScrollProp{
+		value:     value,
+		metadata:  metadata,
+		wrapper:   "data",
+		matchesOn: []string{},
+	}
+}
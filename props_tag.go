@@ -0,0 +1,226 @@
+package inertia
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Struct tag options
+// The props of a struct can declare their behavior with the options of the "prop" tag.
+//
+//	type UsersIndexProps struct {
+//		Users []User              `prop:"users,merge,matchOn=id"`
+//		Roles func() (any, error) `prop:"roles,defer=sidebar"`
+//		Stats func() (any, error) `prop:"stats,optional"`
+//		Flash map[string]any      `prop:"flash,always,omitempty"`
+//	}
+//
+// The options are:
+//   - defer, defer=group: Defer (with the group)
+//   - optional: Optional
+//     The field of defer and optional must be a function that returns the value and optionally an error,
+//     and optionally receives the context, such as func() ([]User, error) and func(context.Context) (Stats, error),
+//     because the value of other types is evaluated before the tag is applied, and nothing is deferred.
+//   - always: Always
+//   - merge, deepMerge, prepend, matchOn=field: Merge and its methods
+//   - omitempty: the prop is omitted if the value is empty
+//   - squash: the fields of the embedded struct are the props
+
+// propTag is the parsed "prop" tag.
+type propTag struct {
+	name      string
+	deferred  bool
+	group     string
+	optional  bool
+	always    bool
+	merge     bool
+	deepMerge bool
+	prepend   bool
+	matchOn   []string
+	squash    bool
+	omitempty bool
+}
+
+func parsePropTag(tag string) (*propTag, error) {
+	name, options, _ := strings.Cut(tag, ",")
+	t := &propTag{name: name}
+	for _, option := range splitAndRemoveEmpty(options, ",") {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "defer":
+			t.deferred = true
+			t.group = value
+		case "optional":
+			t.optional = true
+		case "always":
+			t.always = true
+		case "merge":
+			t.merge = true
+		case "deepMerge":
+			t.merge = true
+			t.deepMerge = true
+		case "prepend":
+			t.merge = true
+			t.prepend = true
+		case "matchOn":
+			t.merge = true
+			t.matchOn = append(t.matchOn, value)
+		case "squash":
+			t.squash = true
+		case "omitempty":
+			t.omitempty = true
+		case "remain":
+			// handled by mapstructure
+		default:
+			return nil, fmt.Errorf("unknown option %q", option)
+		}
+	}
+
+	n := 0
+	for _, b := range []bool{t.deferred, t.optional, t.always} {
+		if b {
+			n++
+		}
+	}
+	if n > 1 {
+		return nil, fmt.Errorf("defer, optional and always can not be used together")
+	}
+	if t.merge && (t.optional || t.always) {
+		return nil, fmt.Errorf("the merge options can not be used with optional and always")
+	}
+	return t, nil
+}
+
+// parseFieldPropTag parses the "prop" tag of the struct field, and validates it for the type of the field.
+func parseFieldPropTag(f reflect.StructField) (*propTag, error) {
+	t, err := parsePropTag(f.Tag.Get("prop"))
+	if err != nil {
+		return nil, err
+	}
+	if (t.deferred || t.optional) && !isTagCallbackType(f.Type) {
+		return nil, fmt.Errorf("defer and optional require a function field like func() (T, error) or func(context.Context) (T, error), but the field is %s", f.Type)
+	}
+	return t, nil
+}
+
+var (
+	contextType = reflect.TypeFor[context.Context]()
+	errorType   = reflect.TypeFor[error]()
+)
+
+// isTagCallbackType reports whether the type is a function that can be the callback of defer and optional.
+// It receives nothing or a context.Context, and returns a value and optionally an error.
+func isTagCallbackType(t reflect.Type) bool {
+	if t.Kind() != reflect.Func || t.IsVariadic() {
+		return false
+	}
+	if t.NumIn() > 1 || (t.NumIn() == 1 && t.In(0) != contextType) {
+		return false
+	}
+	return t.NumOut() == 1 || (t.NumOut() == 2 && t.Out(1) == errorType)
+}
+
+// tagCallback converts the function of a field to a ContextCallback, so that the typed functions can be used.
+// A nil function is evaluated to nil.
+func tagCallback(fn any) ContextCallback {
+	return func(ctx context.Context) (any, error) {
+		v := reflect.ValueOf(fn)
+		if !v.IsValid() || v.IsNil() {
+			return nil, nil
+		}
+		var in []reflect.Value
+		if v.Type().NumIn() == 1 {
+			in = []reflect.Value{reflect.ValueOf(&ctx).Elem()}
+		}
+		out := v.Call(in)
+		if len(out) == 2 && !out[1].IsNil() {
+			return nil, out[1].Interface().(error)
+		}
+		return out[0].Interface(), nil
+	}
+}
+
+// wrap wraps the value with the prop that the tag declares.
+// The value of defer and optional is a function, which is called when the prop is evaluated.
+func (t *propTag) wrap(value any) any {
+	switch {
+	case t.deferred:
+		group := t.group
+		if group == "" {
+			group = "default"
+		}
+		p := DeferCtxWithGroup(tagCallback(value), group)
+		if t.merge {
+			p.Merge()
+			if t.deepMerge {
+				p.DeepMerge()
+			}
+			if t.prepend {
+				p.Prepend()
+			}
+			p.MatchOn(t.matchOn...)
+		}
+		return p
+	case t.optional:
+		return OptionalCtx(tagCallback(value))
+	case t.always:
+		return Always(value)
+	case t.merge:
+		p := Merge(value)
+		if t.deepMerge {
+			p.DeepMerge()
+		}
+		if t.prepend {
+			p.Prepend()
+		}
+		p.MatchOn(t.matchOn...)
+		return p
+	}
+	return value
+}
+
+// applyPropTags wraps the props that are decoded from the struct with the props that the tag options declare.
+func applyPropTags(propsData any, props map[string]any) error {
+	v := reflect.ValueOf(propsData)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	t := v.Type()
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		if !f.IsExported() {
+			continue
+		}
+		tag, err := parseFieldPropTag(f)
+		if err != nil {
+			return fmt.Errorf("inertia-echo: invalid prop tag of the field %s: %w", f.Name, err)
+		}
+		if tag.name == "-" {
+			continue
+		}
+		if tag.squash {
+			if err := applyPropTags(v.Field(n).Interface(), props); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := tag.name
+		if name == "" {
+			name = f.Name
+		}
+		if value, ok := props[name]; ok {
+			props[name] = tag.wrap(value)
+		}
+	}
+	return nil
+}
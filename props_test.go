@@ -1,8 +1,15 @@
 package inertia
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestEvaluatePropValue(t *testing.T) {
@@ -111,6 +118,13 @@ func TestEvaluatePropValue(t *testing.T) {
 			expected: []int{1, 2, 3},
 		},
 
+		// ScrollProp tests
+		{
+			name:     "scroll prop value",
+			input:    Scroll(map[string]any{"data": "scroll value"}, ScrollMetadata{}),
+			expected: map[string]any{"data": "scroll value"},
+		},
+
 		// Function tests - func() (any, error)
 		{
 			name: "function with error return success",
@@ -279,3 +293,953 @@ func TestEvaluateProps(t *testing.T) {
 		})
 	}
 }
+
+func TestEvaluatePropValue_Nested(t *testing.T) {
+	type widget struct {
+		Name   string `json:"name"`
+		Value  any    `json:"value"`
+		Hidden any    `json:"-"`
+	}
+
+	tests := []struct {
+		name     string
+		input    any
+		expected string
+	}{
+		{
+			name: "map",
+			input: map[string]any{
+				"a": Optional(func() (any, error) { return "optional", nil }),
+				"b": map[string]any{"c": func() any { return "callback" }},
+			},
+			expected: `{"a":"optional","b":{"c":"callback"}}`,
+		},
+		{
+			name: "slice",
+			input: []any{
+				Always(1),
+				func() (any, error) { return []any{Merge(2)}, nil },
+			},
+			expected: `[1,[2]]`,
+		},
+		{
+			name: "struct",
+			input: &widget{
+				Name:  "visits",
+				Value: Defer(func() (any, error) { return 10, nil }),
+				Hidden: func() any {
+					t.Error("expected the field that is not marshalled not to be evaluated")
+					return nil
+				},
+			},
+			expected: `{"name":"visits","value":10}`,
+		},
+		{
+			name:     "slice of structs",
+			input:    []widget{{Name: "visits", Value: func() any { return 10 }}},
+			expected: `[{"name":"visits","value":10}]`,
+		},
+		{
+			name:     "typed map",
+			input:    map[string][]any{"a": {func() any { return "callback" }}},
+			expected: `{"a":["callback"]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := evaluatePropValue(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := json.Marshal(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, b)
+			}
+		})
+	}
+}
+
+func TestEvaluatePropValue_CopyOnWrite(t *testing.T) {
+	// The data without nested props is returned as it is.
+	plain := map[string]any{"rows": []any{map[string]any{"id": 1}}}
+	result, err := evaluatePropValue(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.ValueOf(result).Pointer() != reflect.ValueOf(plain).Pointer() {
+		t.Error("expected the plain map not to be copied")
+	}
+
+	// The containers are copied when a nested prop is found after the plain elements.
+	input := map[string]any{
+		"rows":  []any{1, 2, func() any { return 3 }},
+		"array": [2]any{"a", func() any { return "b" }},
+		"plain": "value",
+	}
+	result, err = evaluatePropValue(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{"rows": []any{1, 2, 3}, "array": [2]any{"a", "b"}, "plain": "value"}
+	if !testDeepEqual(t, result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+	if _, ok := input["rows"].([]any)[2].(func() any); !ok {
+		t.Error("expected the given slice not to be modified")
+	}
+}
+
+func TestEvaluatePropValue_NestedError(t *testing.T) {
+	_, err := evaluatePropValue(map[string]any{
+		"a": []any{func() (any, error) { return nil, errors.New("nested error") }},
+	})
+	var pe *PropError
+	if !errors.As(err, &pe) || pe.Path != "a.0" || pe.Err.Error() != "nested error" {
+		t.Errorf("expected the nested error at a.0, got %v", err)
+	}
+}
+
+func TestEvaluateProps_Concurrency(t *testing.T) {
+	const n = 4
+
+	var (
+		mu      sync.Mutex
+		once    sync.Once
+		running int
+		maxRun  int
+	)
+	// The props are blocked until 2 props are running at the same time.
+	release := make(chan struct{})
+	newProp := func(i int) func() (any, error) {
+		return func() (any, error) {
+			mu.Lock()
+			running++
+			if running > maxRun {
+				maxRun = running
+			}
+			if running == 2 {
+				once.Do(func() { close(release) })
+			}
+			mu.Unlock()
+
+			<-release
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return i, nil
+		}
+	}
+
+	values := map[string]any{}
+	for i := 0; i < n; i++ {
+		values[fmt.Sprintf("prop%d", i)] = Optional(newProp(i))
+	}
+
+	e := &propEvaluator{concurrency: 2}
+	if err := e.evaluateProps(values); err != nil {
+		t.Fatal(err)
+	}
+	if maxRun != 2 {
+		t.Errorf("expected 2 props to be evaluated concurrently, got %d", maxRun)
+	}
+	for i := 0; i < n; i++ {
+		if v := values[fmt.Sprintf("prop%d", i)]; v != i {
+			t.Errorf("expected prop%d to be %d, got %v", i, i, v)
+		}
+	}
+}
+
+func TestEvaluateProps_ConcurrencyError(t *testing.T) {
+	values := map[string]any{
+		"a": func() (any, error) { return nil, errors.New("prop error") },
+		"b": func() (any, error) { return "b", nil },
+		"c": func() any { return "c" },
+	}
+
+	e := &propEvaluator{concurrency: 3}
+	err := e.evaluateProps(values)
+	var pe *PropError
+	if !errors.As(err, &pe) || pe.Path != "a" || pe.Err.Error() != "prop error" {
+		t.Errorf("expected the prop error, got %v", err)
+	}
+	if _, ok := values["b"].(func() (any, error)); !ok {
+		t.Error("expected the values not to be updated on error")
+	}
+}
+
+func TestEvaluateProps_Context(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+
+	values := map[string]any{
+		"optional": OptionalCtx(func(ctx context.Context) (any, error) {
+			return ctx.Value(ctxKey{}), nil
+		}),
+		"defer": DeferCtx(func(ctx context.Context) (any, error) {
+			return ctx.Value(ctxKey{}), nil
+		}),
+		"func": func(ctx context.Context) (any, error) {
+			return ctx.Value(ctxKey{}), nil
+		},
+	}
+
+	e := &propEvaluator{ctx: ctx}
+	if err := e.evaluateProps(values); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range values {
+		if v != "request" {
+			t.Errorf("expected %s to receive the request context, got %v", k, v)
+		}
+	}
+}
+
+func TestEvaluateProps_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	e := &propEvaluator{ctx: ctx}
+	err := e.evaluateProps(map[string]any{
+		"a": func() any {
+			t.Error("expected the prop not to be evaluated")
+			return nil
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestEvaluateProps_ConcurrencyCancel(t *testing.T) {
+	e := &propEvaluator{concurrency: 2}
+	err := e.evaluateProps(map[string]any{
+		"a": func() (any, error) { return nil, errors.New("prop error") },
+		"b": func(ctx context.Context) (any, error) {
+			// It is canceled by the error of the other prop.
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	var pe *PropError
+	if !errors.As(err, &pe) || pe.Path != "a" || pe.Err.Error() != "prop error" {
+		t.Errorf("expected the prop error, got %v", err)
+	}
+}
+
+func TestPropTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		prop        any
+		expected    any
+		expectError bool
+	}{
+		{
+			name: "in time",
+			prop: Optional(func() (any, error) {
+				return "value", nil
+			}).Timeout(time.Second, "fallback"),
+			expected: "value",
+		},
+		{
+			name: "timeout",
+			prop: Defer(func() (any, error) {
+				time.Sleep(100 * time.Millisecond)
+				return "value", nil
+			}).Timeout(10*time.Millisecond, "fallback"),
+			expected: "fallback",
+		},
+		{
+			name: "timeout of a context-aware callback",
+			prop: DeferCtx(func(ctx context.Context) (any, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}).Timeout(10*time.Millisecond, []string{}),
+			expected: []string{},
+		},
+		{
+			name: "error in time",
+			prop: OptionalCtx(func(ctx context.Context) (any, error) {
+				return nil, errors.New("prop error")
+			}).Timeout(time.Second, "fallback"),
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := evaluatePropValue(tt.prop)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !testDeepEqual(t, result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestPropError(t *testing.T) {
+	errProp := errors.New("prop error")
+
+	tests := []struct {
+		name          string
+		props         map[string]any
+		expectedPath  string
+		expectedKind  string
+		expectedErr   error
+		expectedPanic bool
+		concurrency   int
+	}{
+		{
+			name: "defer prop",
+			props: map[string]any{
+				"permissions": Defer(func() (any, error) { return nil, errProp }),
+			},
+			expectedPath: "permissions",
+			expectedKind: "defer",
+			expectedErr:  errProp,
+		},
+		{
+			name: "nested prop",
+			props: map[string]any{
+				"user": map[string]any{
+					"teams": []any{Always(func() (any, error) { return nil, errProp })},
+				},
+			},
+			expectedPath: "user.teams.0",
+			expectedKind: "always",
+			expectedErr:  errProp,
+		},
+		{
+			name: "struct field",
+			props: map[string]any{
+				"widget": struct {
+					Value any `json:"value"`
+				}{Value: Optional(func() (any, error) { return nil, errProp })},
+			},
+			expectedPath: "widget.value",
+			expectedKind: "optional",
+			expectedErr:  errProp,
+		},
+		{
+			name: "panic",
+			props: map[string]any{
+				"stats": func() any { panic("boom") },
+			},
+			expectedPath:  "stats",
+			expectedKind:  "callback",
+			expectedPanic: true,
+		},
+		{
+			name: "panic in a defer prop",
+			props: map[string]any{
+				"stats": Defer(func() (any, error) { panic("boom") }),
+			},
+			expectedPath:  "stats",
+			expectedKind:  "defer",
+			expectedPanic: true,
+		},
+		{
+			name: "panic in an optional prop",
+			props: map[string]any{
+				"stats": Optional(func() (any, error) { panic("boom") }),
+			},
+			expectedPath:  "stats",
+			expectedKind:  "optional",
+			expectedPanic: true,
+		},
+		{
+			name: "panic in a defer prop evaluated concurrently",
+			props: map[string]any{
+				"stats": Defer(func() (any, error) { panic("boom") }),
+				"users": []string{"John"},
+			},
+			expectedPath:  "stats",
+			expectedKind:  "defer",
+			expectedPanic: true,
+			concurrency:   4,
+		},
+		{
+			name: "panic in an optional prop evaluated concurrently",
+			props: map[string]any{
+				"stats": Optional(func() (any, error) { panic("boom") }),
+				"users": []string{"John"},
+			},
+			expectedPath:  "stats",
+			expectedKind:  "optional",
+			expectedPanic: true,
+			concurrency:   4,
+		},
+		{
+			name: "panic in a prop with timeout",
+			props: map[string]any{
+				"stats": Defer(func() (any, error) { panic("boom") }).Timeout(time.Second, nil),
+			},
+			expectedPath:  "stats",
+			expectedKind:  "defer",
+			expectedPanic: true,
+		},
+		{
+			name: "not wrapped twice",
+			props: map[string]any{
+				"outer": func() (any, error) {
+					// The error of a nested render is returned as it is.
+					inner := map[string]any{
+						"inner": Optional(func() (any, error) { return nil, errProp }),
+					}
+					return inner, (&propEvaluator{component: "Dashboard"}).evaluateProps(inner)
+				},
+			},
+			expectedPath: "inner",
+			expectedKind: "optional",
+			expectedErr:  errProp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &propEvaluator{component: "Dashboard", concurrency: tt.concurrency}
+			err := e.evaluateProps(tt.props)
+
+			var pe *PropError
+			if !errors.As(err, &pe) {
+				t.Fatalf("expected PropError, got %v", err)
+			}
+			if pe.Path != tt.expectedPath {
+				t.Errorf("expected path %q, got %q", tt.expectedPath, pe.Path)
+			}
+			if pe.Kind != tt.expectedKind {
+				t.Errorf("expected kind %q, got %q", tt.expectedKind, pe.Kind)
+			}
+			if tt.expectedErr != nil && !errors.Is(err, tt.expectedErr) {
+				t.Errorf("expected the error to wrap %v, got %v", tt.expectedErr, pe.Err)
+			}
+			if (pe.Stack != nil) != tt.expectedPanic {
+				t.Errorf("expected stack only on panic, got %q", pe.Stack)
+			}
+			if pe.Component != "Dashboard" {
+				t.Errorf("expected component Dashboard, got %q", pe.Component)
+			}
+		})
+	}
+}
+
+func TestFallbackProp(t *testing.T) {
+	errProp := errors.New("prop error")
+
+	tests := []struct {
+		name         string
+		prop         any
+		expected     any
+		expectedPath string
+	}{
+		{
+			name:     "success",
+			prop:     Fallback(Optional(func() (any, error) { return "value", nil }), "fallback"),
+			expected: "value",
+		},
+		{
+			name:         "error",
+			prop:         Fallback(Defer(func() (any, error) { return nil, errProp }), "fallback"),
+			expected:     "fallback",
+			expectedPath: "prop",
+		},
+		{
+			name:         "panic",
+			prop:         Fallback(func() any { panic("boom") }, 0),
+			expected:     0,
+			expectedPath: "prop",
+		},
+		{
+			name: "nested error",
+			prop: Fallback(map[string]any{
+				"items": []any{func() (any, error) { return nil, errProp }},
+			}, []any{}),
+			expected:     []any{},
+			expectedPath: "prop.items.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reported *PropError
+			e := &propEvaluator{onPropError: func(err *PropError) { reported = err }}
+			values := map[string]any{"prop": tt.prop}
+			if err := e.evaluateProps(values); err != nil {
+				t.Fatal(err)
+			}
+
+			if !testDeepEqual(t, values["prop"], tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, values["prop"])
+			}
+			if tt.expectedPath == "" {
+				if reported != nil {
+					t.Errorf("expected no error to be reported, got %v", reported)
+				}
+				return
+			}
+			if reported == nil || reported.Path != tt.expectedPath {
+				t.Errorf("expected the error of %q to be reported, got %v", tt.expectedPath, reported)
+			}
+		})
+	}
+}
+
+func TestFallbackProp_CanceledRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		prop func(cancel context.CancelFunc) any
+	}{
+		{
+			name: "canceled",
+			prop: func(cancel context.CancelFunc) any {
+				return Fallback(DeferCtx(func(ctx context.Context) (any, error) {
+					cancel()
+					return nil, ctx.Err()
+				}), "fallback")
+			},
+		},
+		{
+			name: "wrapped",
+			prop: func(cancel context.CancelFunc) any {
+				return Fallback(func(ctx context.Context) (any, error) {
+					cancel()
+					return nil, fmt.Errorf("failed to load: %w", ctx.Err())
+				}, "fallback")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var reported *PropError
+			e := &propEvaluator{ctx: ctx, onPropError: func(err *PropError) { reported = err }}
+			values := map[string]any{"prop": tt.prop(cancel)}
+			err := e.evaluateProps(values)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got %v", err)
+			}
+			if reported != nil {
+				t.Errorf("expected no error to be reported, got %v", reported)
+			}
+		})
+	}
+}
+
+func TestTypedProps(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+	listUsers := func(ctx context.Context) ([]user, error) {
+		return []user{{Name: "John"}}, nil
+	}
+
+	tests := []struct {
+		name            string
+		prop            any
+		expected        string
+		ignoreFirstLoad bool
+		mergeable       bool
+	}{
+		{
+			name:            "DeferOf",
+			prop:            DeferOf(listUsers),
+			expected:        `[{"name":"John"}]`,
+			ignoreFirstLoad: true,
+		},
+		{
+			name:            "DeferOfWithGroup",
+			prop:            DeferOfWithGroup(listUsers, "users").Append(),
+			expected:        `[{"name":"John"}]`,
+			ignoreFirstLoad: true,
+			mergeable:       true,
+		},
+		{
+			name:            "OptionalOf",
+			prop:            OptionalOf(listUsers),
+			expected:        `[{"name":"John"}]`,
+			ignoreFirstLoad: true,
+		},
+		{
+			name:     "OnceOf",
+			prop:     OnceOf(listUsers),
+			expected: `[{"name":"John"}]`,
+		},
+		{
+			name: "LazyOf",
+			prop: LazyOf(func() ([]user, error) {
+				return listUsers(context.Background())
+			}),
+			expected:        `[{"name":"John"}]`,
+			ignoreFirstLoad: true,
+		},
+		{
+			name:     "AlwaysOf",
+			prop:     AlwaysOf(listUsers),
+			expected: `[{"name":"John"}]`,
+		},
+		{
+			name:      "MergeOf",
+			prop:      MergeOf(listUsers),
+			expected:  `[{"name":"John"}]`,
+			mergeable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := tt.prop.(IgnoreFirstLoadProp); ok != tt.ignoreFirstLoad {
+				t.Errorf("expected IgnoreFirstLoadProp: %v", tt.ignoreFirstLoad)
+			}
+			if m, ok := tt.prop.(Mergeable); (ok && m.ShouldMerge()) != tt.mergeable {
+				t.Errorf("expected Mergeable: %v", tt.mergeable)
+			}
+
+			result, err := evaluatePropValue(tt.prop)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := json.Marshal(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, b)
+			}
+		})
+	}
+}
+
+func TestTypedProps_Error(t *testing.T) {
+	errProp := errors.New("prop error")
+	_, err := evaluatePropValue(DeferOf(func(ctx context.Context) (*struct{}, error) {
+		return nil, errProp
+	}))
+	if !errors.Is(err, errProp) {
+		t.Errorf("expected the prop error, got %v", err)
+	}
+}
+
+func TestParsePropTag(t *testing.T) {
+	tests := []struct {
+		tag         string
+		expected    *propTag
+		expectError bool
+	}{
+		{tag: "users", expected: &propTag{name: "users"}},
+		{tag: "users,defer", expected: &propTag{name: "users", deferred: true}},
+		{tag: "users,defer=sidebar,merge", expected: &propTag{name: "users", deferred: true, group: "sidebar", merge: true}},
+		{tag: "feed,matchOn=id,matchOn=uuid", expected: &propTag{name: "feed", merge: true, matchOn: []string{"id", "uuid"}}},
+		{tag: ",squash", expected: &propTag{squash: true}},
+		{tag: "stats,optional,omitempty", expected: &propTag{name: "stats", optional: true}},
+		{tag: "stats,optinal", expectError: true},
+		{tag: "stats,optional,always", expectError: true},
+		{tag: "stats,always,merge", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			tag, err := parsePropTag(tt.tag)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tag.name != tt.expected.name || tag.deferred != tt.expected.deferred || tag.group != tt.expected.group ||
+				tag.optional != tt.expected.optional || tag.always != tt.expected.always || tag.merge != tt.expected.merge ||
+				tag.squash != tt.expected.squash || strings.Join(tag.matchOn, ",") != strings.Join(tt.expected.matchOn, ",") {
+				t.Errorf("expected %+v, got %+v", tt.expected, tag)
+			}
+		})
+	}
+}
+
+func TestDecodeProps_TypedCallbacks(t *testing.T) {
+	errStats := errors.New("stats error")
+	type typedProps struct {
+		Users func() ([]string, error)                `prop:"users,defer"`
+		Roles func(context.Context) ([]string, error) `prop:"roles,optional"`
+		Count func() int                              `prop:"count,optional"`
+		Empty func() (int, error)                     `prop:"empty,optional"`
+		Stats func() (int, error)                     `prop:"stats,optional"`
+	}
+	data := typedProps{
+		Users: func() ([]string, error) { return []string{"John"}, nil },
+		Roles: func(ctx context.Context) ([]string, error) { return []string{"admin"}, ctx.Err() },
+		Count: func() int { return 10 },
+		Stats: func() (int, error) { return 0, errStats },
+	}
+
+	for _, mode := range []PropsDecodeMode{PropsDecodeMapstructure, PropsDecodeJSON} {
+		props, err := decodeProps(data, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := props["users"].(*DeferProp); !ok {
+			t.Errorf("expected users to be a DeferProp, got %T", props["users"])
+		}
+
+		stats := props["stats"]
+		delete(props, "stats")
+		e := &propEvaluator{}
+		if err := e.evaluateProps(props); err != nil {
+			t.Fatal(err)
+		}
+		b, err := json.Marshal(props)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := `{"count":10,"empty":null,"roles":["admin"],"users":["John"]}`; string(b) != expected {
+			t.Errorf("expected %s, got %s", expected, b)
+		}
+		if err := e.evaluateProps(map[string]any{"stats": stats}); !errors.Is(err, errStats) {
+			t.Errorf("expected the error of the callback, got %v", err)
+		}
+	}
+}
+
+func TestDecodeProps_InvalidTag(t *testing.T) {
+	type deferredProps struct {
+		Users []string `prop:"users,defer"`
+	}
+	type OptionalProps struct {
+		Stats int `prop:"stats,optional"`
+	}
+	type embeddedProps struct {
+		OptionalProps `prop:",squash"`
+	}
+	type argumentProps struct {
+		Users func(limit int) ([]string, error) `prop:"users,defer"`
+	}
+	type resultsProps struct {
+		Users func() ([]string, string) `prop:"users,optional"`
+	}
+
+	tests := []struct {
+		name string
+		data any
+	}{
+		{name: "defer", data: deferredProps{Users: []string{"John"}}},
+		{name: "optional", data: &OptionalProps{Stats: 10}},
+		{name: "squash", data: embeddedProps{}},
+		{name: "function with an argument", data: argumentProps{}},
+		{name: "function without an error", data: resultsProps{}},
+	}
+
+	for _, tt := range tests {
+		for _, mode := range []PropsDecodeMode{PropsDecodeMapstructure, PropsDecodeJSON} {
+			t.Run(tt.name, func(t *testing.T) {
+				if _, err := decodeProps(tt.data, mode); err == nil {
+					t.Error("expected error but got none")
+				}
+			})
+		}
+	}
+}
+
+type testMarshalerProp struct{}
+
+func (testMarshalerProp) MarshalJSON() ([]byte, error) {
+	return []byte(`"marshaled"`), nil
+}
+
+func TestDecodeProps(t *testing.T) {
+	type Author struct {
+		Name      string    `json:"name"`
+		CreatedAt time.Time `json:"created_at"`
+		Secret    string    `json:"-"`
+	}
+	type Base struct {
+		AppName string `prop:"appName"`
+	}
+	type postProps struct {
+		Base
+		Author    Author            `prop:"author"`
+		Custom    testMarshalerProp `prop:"custom"`
+		Comments  func() any        `prop:"comments,optional"`
+		Note      string            `prop:"note,omitempty"`
+		Untagged  int
+		Ignored   string `prop:"-"`
+		unexposed string
+	}
+	data := &postProps{
+		Base:     Base{AppName: "app"},
+		Author:   Author{Name: "John", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Secret: "secret"},
+		Comments: func() any { return []string{"comment"} },
+		Untagged: 1,
+		Ignored:  "ignored",
+	}
+
+	tests := []struct {
+		name     string
+		mode     PropsDecodeMode
+		expected string
+	}{
+		{
+			name:     "mapstructure",
+			mode:     PropsDecodeMapstructure,
+			expected: `{"Base":{"appName":"app"},"Untagged":1,"author":{"CreatedAt":{},"Name":"John","Secret":"secret"},"custom":{}}`,
+		},
+		{
+			name:     "json",
+			mode:     PropsDecodeJSON,
+			expected: `{"Untagged":1,"appName":"app","author":{"name":"John","created_at":"2024-01-02T03:04:05Z"},"custom":"marshaled"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			props, err := decodeProps(data, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := props["comments"].(*OptionalProp); !ok {
+				t.Errorf("expected comments to be an optional prop, got %T", props["comments"])
+			}
+			delete(props, "comments")
+
+			b, err := json.Marshal(props)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, b)
+			}
+		})
+	}
+}
+
+type testEmbeddedProps struct {
+	Title string `prop:"title"`
+}
+
+func TestDecodeProps_Values(t *testing.T) {
+	type Author struct {
+		Name string `prop:"name"`
+	}
+	type pageProps struct {
+		testEmbeddedProps
+		*Author `prop:",squash"`
+		Count   int `prop:"count"`
+	}
+
+	tests := []struct {
+		name      string
+		propsData any
+		expected  string
+	}{
+		{
+			name:      "nil",
+			propsData: nil,
+			expected:  `{}`,
+		},
+		{
+			name:      "nil pointer",
+			propsData: (*pageProps)(nil),
+			expected:  `{}`,
+		},
+		{
+			name:      "map of strings",
+			propsData: map[string]string{"title": "Home"},
+			expected:  `{"title":"Home"}`,
+		},
+	}
+
+	modes := map[string]PropsDecodeMode{
+		"mapstructure": PropsDecodeMapstructure,
+		"json":         PropsDecodeJSON,
+	}
+	for modeName, mode := range modes {
+		for _, tt := range tests {
+			t.Run(modeName+"/"+tt.name, func(t *testing.T) {
+				props, err := decodeProps(tt.propsData, mode)
+				if err != nil {
+					t.Fatal(err)
+				}
+				b, err := json.Marshal(props)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(b) != tt.expected {
+					t.Errorf("expected %s, got %s", tt.expected, b)
+				}
+			})
+		}
+	}
+
+	t.Run("unexported embedded struct", func(t *testing.T) {
+		// The fields of an unexported embedded struct are promoted like encoding/json.
+		props, err := decodeProps(pageProps{
+			testEmbeddedProps: testEmbeddedProps{Title: "Home"},
+			Author:            &Author{Name: "John"},
+			Count:             1,
+		}, PropsDecodeJSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := json.Marshal(props)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := `{"count":1,"name":"John","title":"Home"}`; string(b) != expected {
+			t.Errorf("expected %s, got %s", expected, b)
+		}
+	})
+}
+
+func TestPropTimeout_CancelsCallback(t *testing.T) {
+	stopped := make(chan struct{})
+	prop := DeferCtx(func(ctx context.Context) (any, error) {
+		// The callback that honors the context stops at the timeout.
+		<-ctx.Done()
+		close(stopped)
+		return nil, ctx.Err()
+	}).Timeout(10*time.Millisecond, "fallback")
+
+	result, err := evaluatePropValue(prop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "fallback" {
+		t.Errorf("expected fallback, got %v", result)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Error("expected the context of the callback to be canceled at the timeout")
+	}
+}
+
+func BenchmarkEvaluateProps_PlainData(b *testing.B) {
+	rows := make([]any, 5000)
+	for n := range rows {
+		rows[n] = map[string]any{"id": n, "name": "John", "email": "john@example.com", "tags": []any{"admin", "user"}}
+	}
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		e := &propEvaluator{}
+		if err := e.evaluateProps(map[string]any{"rows": rows}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
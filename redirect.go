@@ -0,0 +1,133 @@
+package inertia
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Back redirects to the previous page.
+// The previous page is determined by the Referer header.
+// If the Referer header is missing or points to another host, it redirects to MiddlewareConfig.BackFallbackURL.
+func (i *Inertia) Back() error {
+	return i.echoContext.Redirect(http.StatusFound, i.previousURL())
+}
+
+// BackWithErrors redirects to the previous page with the validation errors and the submitted input.
+// The errors and the input are sent to the client as the "errors" and "old" props on the next render.
+// If WithInput has not been called, the submitted form parameters are used as the input.
+//
+// Note:
+// A JSON request body is usually consumed by binding it in the handler, so it can not be read here.
+// Pass the bound value to WithInput if you need the input of a JSON request.
+func (i *Inertia) BackWithErrors(errs ValidationErrors) error {
+	i.WithErrors(errs)
+	i.mu.RLock()
+	hasInput := i.oldInput != nil
+	i.mu.RUnlock()
+	if !hasInput {
+		i.WithInput(i.formInput())
+	}
+	return i.Back()
+}
+
+// WithInput stores the submitted input for the next render.
+// The input can be a map or a struct, which is converted to a map with encoding/json.
+// The fields listed in MiddlewareConfig.DontFlash are never stored.
+func (i *Inertia) WithInput(input any) {
+	m, ok := input.(map[string]any)
+	if !ok {
+		b, err := json.Marshal(input)
+		if err != nil {
+			i.echoContext.Logger().Errorf("inertia-echo: failed to encode input: %v", err)
+			return
+		}
+		if err := json.Unmarshal(b, &m); err != nil {
+			i.echoContext.Logger().Errorf("inertia-echo: failed to decode input: %v", err)
+			return
+		}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.oldInput == nil {
+		i.oldInput = map[string]any{}
+	}
+	for k, v := range m {
+		if inArray(k, i.dontFlash) {
+			continue
+		}
+		i.oldInput[k] = v
+	}
+}
+
+// Old returns the input that was submitted by the previous request.
+func (i *Inertia) Old(key string) any {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.oldInput[key]
+}
+
+func (i *Inertia) previousURL() string {
+	referer := i.echoContext.Request().Header.Get("Referer")
+	if referer == "" || !i.isLocalURL(referer) {
+		return i.backFallbackURL
+	}
+	return referer
+}
+
+// isLocalURL reports whether the URL points to the current host, to prevent open redirects to other hosts.
+// It accepts an absolute URL with the same scheme and host, or a path that starts with a single slash.
+// A path like "//evil.com" or "/\evil.com" is rejected, because browsers resolve it to another host.
+func (i *Inertia) isLocalURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	if u.Scheme != "" || u.Host != "" {
+		return u.Scheme == i.echoContext.Scheme() && u.Host == i.echoContext.Request().Host && u.Opaque == ""
+	}
+	return strings.HasPrefix(rawURL, "/") && !strings.HasPrefix(rawURL, "//") && !strings.HasPrefix(rawURL, "/\\")
+}
+
+func (i *Inertia) formInput() map[string]any {
+	req := i.echoContext.Request()
+	ctype := req.Header.Get(echo.HeaderContentType)
+	if !strings.HasPrefix(ctype, echo.MIMEApplicationForm) && !strings.HasPrefix(ctype, echo.MIMEMultipartForm) {
+		return nil
+	}
+
+	params, err := i.echoContext.FormParams()
+	if err != nil {
+		return nil
+	}
+
+	input := make(map[string]any, len(params))
+	for k, v := range params {
+		if len(v) == 1 {
+			input[k] = v[0]
+		} else {
+			input[k] = v
+		}
+	}
+	return input
+}
+
+func Back(c echo.Context) error {
+	return MustGet(c).Back()
+}
+
+func BackWithErrors(c echo.Context, errs ValidationErrors) error {
+	return MustGet(c).BackWithErrors(errs)
+}
+
+func WithInput(c echo.Context, input any) {
+	MustGet(c).WithInput(input)
+}
+
+func Old(c echo.Context, key string) any {
+	return MustGet(c).Old(key)
+}
@@ -0,0 +1,174 @@
+package inertia
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestBack(t *testing.T) {
+	tests := []struct {
+		name     string
+		referer  string
+		expected string
+	}{
+		{
+			name:     "with referer",
+			referer:  "http://example.com/users/create",
+			expected: "http://example.com/users/create",
+		},
+		{
+			name:     "with relative referer",
+			referer:  "/users/create",
+			expected: "/users/create",
+		},
+		{
+			name:     "without referer",
+			expected: "/home",
+		},
+		{
+			name:     "referer to another host",
+			referer:  "http://evil.example.org/",
+			expected: "/home",
+		},
+		{
+			name:     "referer to another scheme",
+			referer:  "https://example.com/users/create",
+			expected: "/home",
+		},
+		{
+			name:     "protocol-relative referer",
+			referer:  "//evil.example.org/",
+			expected: "/home",
+		},
+		{
+			name:     "referer with a backslash after the slash",
+			referer:  "/\\evil.example.org",
+			expected: "/home",
+		},
+		{
+			name:     "referer with backslashes",
+			referer:  "\\\\evil.example.org",
+			expected: "/home",
+		},
+		{
+			name:     "referer with a scheme and without slashes",
+			referer:  "http:evil.example.org",
+			expected: "/home",
+		},
+		{
+			name:     "referer without a leading slash",
+			referer:  "evil.example.org",
+			expected: "/home",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://example.com/users", nil)
+			if tt.referer != "" {
+				req.Header.Set("Referer", tt.referer)
+			}
+			rec := httptest.NewRecorder()
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			m := MiddlewareWithConfig(MiddlewareConfig{
+				FlashStore:      NewMemoryFlashStore(),
+				BackFallbackURL: "/home",
+			})
+
+			if err := m(Back)(c); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusFound {
+				t.Errorf("expected status 302, got %d", rec.Code)
+			}
+			if loc := rec.Header().Get(echo.HeaderLocation); loc != tt.expected {
+				t.Errorf("expected location %q, got %q", tt.expected, loc)
+			}
+		})
+	}
+}
+
+func TestBackWithErrors(t *testing.T) {
+	store := NewMemoryFlashStore()
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Renderer:    testNewMockRenderer(t, func(ctx *RenderContext) error { return nil }),
+		VersionFunc: func() string { return "" },
+		FlashStore:  store,
+	}))
+	e.POST("/users", func(c echo.Context) error {
+		return BackWithErrors(c, ValidationErrors{"email": "Invalid email address"})
+	})
+	e.GET("/users/create", func(c echo.Context) error {
+		if Old(c, "email") != "invalid" {
+			t.Errorf("expected old email, got %v", Old(c, "email"))
+		}
+		return Render(c, "Users/Create", nil)
+	})
+
+	form := url.Values{}
+	form.Set("email", "invalid")
+	form.Set("password", "secret")
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	req.Header.Set(HeaderXInertia, "true")
+	req.Header.Set("Referer", "/users/create")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if loc := rec.Header().Get(echo.HeaderLocation); loc != "/users/create" {
+		t.Fatalf("expected redirect to /users/create, got %q", loc)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/users/create", nil)
+	req.Header.Set(HeaderXInertia, "true")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var page Page
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if !testDeepEqual(t, page.Props["errors"], map[string]any{"email": "Invalid email address"}) {
+		t.Errorf("unexpected errors: %v", page.Props["errors"])
+	}
+	// the password should not be stored
+	if !testDeepEqual(t, page.Props["old"], map[string]any{"email": "invalid"}) {
+		t.Errorf("unexpected old input: %v", page.Props["old"])
+	}
+}
+
+func TestWithInput(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	rec := httptest.NewRecorder()
+	e := echo.New()
+	c := e.NewContext(req, rec)
+	m := MiddlewareWithConfig(MiddlewareConfig{
+		FlashStore: NewMemoryFlashStore(),
+	})
+
+	type Form struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	}
+
+	err := m(func(c echo.Context) error {
+		WithInput(c, &Form{Name: "John", Password: "secret"})
+		if Old(c, "name") != "John" {
+			t.Errorf("expected name, got %v", Old(c, "name"))
+		}
+		if Old(c, "password") != nil {
+			t.Errorf("expected password not to be stored, got %v", Old(c, "password"))
+		}
+		return nil
+	})(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
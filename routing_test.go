@@ -18,12 +18,16 @@ func TestHandler(t *testing.T) {
 			if ctx.Page.Component != "About" {
 				t.Errorf("expected component: %s, got: %s", "About", ctx.Page.Component)
 			}
-			// When no props are provided, should default to empty map
-			if len(ctx.Page.Props) != 0 {
-				t.Errorf("expected empty props, got: %v", ctx.Page.Props)
+			// When no props are provided, only the "errors" prop should be present
+			if len(ctx.Page.Props) != 1 {
+				t.Errorf("expected only errors prop, got: %v", ctx.Page.Props)
+			}
+			if !testDeepEqual(t, ctx.Page.Props["errors"], map[string]any{}) {
+				t.Errorf("expected empty errors, got: %v", ctx.Page.Props["errors"])
 			}
 			return nil
 		}),
+		FlashStore: NewMemoryFlashStore(),
 	})
 
 	err := m(Handler("About"))(c)
@@ -51,6 +55,7 @@ func TestHandlerWithProps(t *testing.T) {
 			}
 			return nil
 		}),
+		FlashStore: NewMemoryFlashStore(),
 	})
 
 	err := m(HandlerWithProps("About", map[string]any{
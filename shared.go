@@ -0,0 +1,114 @@
+package inertia
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SharedPropsMergeMode is the way to merge the shared props and the page props.
+// see https://inertiajs.com/shared-data
+type SharedPropsMergeMode int
+
+const (
+	// SharedPropsMergeShallow overwrites the shared props with the page props that have the same top-level key.
+	SharedPropsMergeShallow SharedPropsMergeMode = iota
+	// SharedPropsMergeDeep merges the nested maps (map[string]any) of the shared props and the page props recursively.
+	// The keys with dot notation, like "auth.can", are expanded to the nested maps.
+	// The values that are not maps, such as slices and callbacks, are overwritten.
+	SharedPropsMergeDeep
+)
+
+// lazyData calls a function at the first time that its data is needed, and keeps the result.
+// The callers that come while the function is running wait until it returns.
+type lazyData struct {
+	once sync.Once
+	mu   sync.RWMutex
+	data map[string]any
+	err  error
+}
+
+// get returns the data that the function returns.
+// The function must not call get of the same lazyData, because it waits for itself.
+func (l *lazyData) get(f func() (map[string]any, error)) (map[string]any, error) {
+	l.once.Do(func() {
+		data, err := f()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.data, l.err = data, err
+	})
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.data, l.err
+}
+
+// reset discards the data, and prevents the function from being called.
+func (l *lazyData) reset() {
+	l.once.Do(func() {})
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.data, l.err = nil, nil
+}
+
+// copyNestedMaps returns a copy of the props whose nested maps (map[string]any) are copied recursively.
+func copyNestedMaps(props map[string]any) map[string]any {
+	copied := make(map[string]any, len(props))
+	for k, v := range props {
+		if m, ok := v.(map[string]any); ok {
+			v = copyNestedMaps(m)
+		}
+		copied[k] = v
+	}
+	return copied
+}
+
+// deepMergeProps merges src into dst recursively.
+// dst is modified, but the nested maps are copied before they are modified, so that the given maps are not modified.
+func deepMergeProps(dst, src map[string]any) {
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]any)
+		dstMap, dstIsMap := dst[k].(map[string]any)
+		if !srcIsMap || !dstIsMap {
+			dst[k] = v
+			continue
+		}
+
+		merged := make(map[string]any, len(dstMap)+len(srcMap))
+		for kk, vv := range dstMap {
+			merged[kk] = vv
+		}
+		deepMergeProps(merged, srcMap)
+		dst[k] = merged
+	}
+}
+
+// expandDotKeys returns the props whose keys with dot notation are expanded to nested maps.
+// For example, {"auth.can": v} is expanded to {"auth": {"can": v}}.
+func expandDotKeys(props map[string]any) map[string]any {
+	expanded := make(map[string]any, len(props))
+	var dotKeys []string
+	for k, v := range props {
+		if strings.Contains(k, ".") {
+			dotKeys = append(dotKeys, k)
+			continue
+		}
+		expanded[k] = v
+	}
+	if len(dotKeys) == 0 {
+		return expanded
+	}
+
+	// The shorter keys are applied first, so that "auth.user.name" is merged into "auth.user".
+	sort.Strings(dotKeys)
+	for _, k := range dotKeys {
+		path := splitPropPath(k)
+		var value any = props[k]
+		for n := len(path) - 1; n > 0; n-- {
+			value = map[string]any{path[n]: value}
+		}
+		deepMergeProps(expanded, map[string]any{path[0]: value})
+	}
+	return expanded
+}
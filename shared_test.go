@@ -0,0 +1,234 @@
+package inertia
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestExpandDotKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		props    map[string]any
+		expected string
+	}{
+		{
+			name:     "no dot keys",
+			props:    map[string]any{"auth": map[string]any{"user": "John"}},
+			expected: `{"auth":{"user":"John"}}`,
+		},
+		{
+			name:     "dot key",
+			props:    map[string]any{"auth.can": map[string]any{"edit": true}},
+			expected: `{"auth":{"can":{"edit":true}}}`,
+		},
+		{
+			name: "dot keys merged into a map",
+			props: map[string]any{
+				"auth":           map[string]any{"user": map[string]any{"id": 1}},
+				"auth.user.name": "John",
+				"auth.can.edit":  true,
+			},
+			expected: `{"auth":{"can":{"edit":true},"user":{"id":1,"name":"John"}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(expandDotKeys(tt.props))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, b)
+			}
+		})
+	}
+}
+
+func TestDeepMergeProps(t *testing.T) {
+	shared := map[string]any{
+		"auth": map[string]any{
+			"user": map[string]any{"name": "John"},
+			"can":  map[string]any{"view": true},
+		},
+		"tags": []string{"a"},
+	}
+	page := map[string]any{
+		"auth": map[string]any{
+			"can": map[string]any{"edit": true},
+		},
+		"tags": []string{"b"},
+	}
+
+	merged := map[string]any{}
+	deepMergeProps(merged, shared)
+	deepMergeProps(merged, page)
+
+	b, err := json.Marshal(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"auth":{"can":{"edit":true,"view":true},"user":{"name":"John"}},"tags":["b"]}`; string(b) != expected {
+		t.Errorf("expected %s, got %s", expected, b)
+	}
+
+	// The given maps are not modified.
+	if b, _ := json.Marshal(shared); string(b) != `{"auth":{"can":{"view":true},"user":{"name":"John"}},"tags":["a"]}` {
+		t.Errorf("expected the shared props not to be modified, got %s", b)
+	}
+}
+
+func TestRender_SharedPropsMergeMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     SharedPropsMergeMode
+		expected string
+	}{
+		{
+			name:     "shallow",
+			mode:     SharedPropsMergeShallow,
+			expected: `{"auth":{"can":{"edit":true}},"auth.can":{"delete":true},"errors":{}}`,
+		},
+		{
+			name:     "deep",
+			mode:     SharedPropsMergeDeep,
+			expected: `{"auth":{"can":{"delete":true,"edit":true},"user":"John"},"errors":{}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(MiddlewareWithConfig(MiddlewareConfig{
+				Renderer:             testNewMockRenderer(t, func(ctx *RenderContext) error { return nil }),
+				VersionFunc:          func() string { return "" },
+				FlashStore:           NewMemoryFlashStore(),
+				SharedPropsMergeMode: tt.mode,
+				Share: func(c echo.Context) (map[string]any, error) {
+					return map[string]any{"auth": map[string]any{"user": "John"}}, nil
+				},
+			}))
+			e.GET("/", func(c echo.Context) error {
+				Share(c, map[string]any{"auth.can": map[string]any{"delete": true}})
+				return Render(c, "Home", map[string]any{
+					"auth": map[string]any{"can": map[string]any{"edit": true}},
+				})
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(HeaderXInertia, "true")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			var page Page
+			if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+				t.Fatal(err)
+			}
+			b, err := json.Marshal(page.Props)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, b)
+			}
+		})
+	}
+}
+
+func TestShared_Concurrent(t *testing.T) {
+	calls := 0
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		FlashStore: NewMemoryFlashStore(),
+		Share: func(c echo.Context) (map[string]any, error) {
+			calls++
+			// The other callers wait for the shared data instead of getting nothing.
+			time.Sleep(10 * time.Millisecond)
+			return map[string]any{"appName": "app"}, nil
+		},
+	}))
+	e.GET("/", func(c echo.Context) error {
+		var wg sync.WaitGroup
+		for n := 0; n < 4; n++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if v := Shared(c)["appName"]; v != "app" {
+					t.Errorf("expected appName to be app, got %v", v)
+				}
+			}()
+		}
+		wg.Wait()
+		return nil
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if calls != 1 {
+		t.Errorf("expected Share to be called once, got %d calls", calls)
+	}
+}
+
+func TestShared_ShareError(t *testing.T) {
+	errShare := errors.New("share error")
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Renderer:    testNewMockRenderer(t, func(ctx *RenderContext) error { return nil }),
+		VersionFunc: func() string { return "" },
+		FlashStore:  NewMemoryFlashStore(),
+		Share: func(c echo.Context) (map[string]any, error) {
+			return nil, errShare
+		},
+	}))
+	var renderErr error
+	e.GET("/", func(c echo.Context) error {
+		Share(c, map[string]any{"app": "app"})
+
+		// The error is logged, and only the props shared manually are returned.
+		if shared := Shared(c); !testDeepEqual(t, shared, map[string]any{"app": "app"}) {
+			t.Errorf("expected only the props shared manually, got %v", shared)
+		}
+		renderErr = Render(c, "Home", map[string]any{})
+		return nil
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !errors.Is(renderErr, errShare) {
+		t.Errorf("expected the error of Share, got %v", renderErr)
+	}
+}
+
+func TestShared_Snapshot(t *testing.T) {
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		FlashStore: NewMemoryFlashStore(),
+		Share: func(c echo.Context) (map[string]any, error) {
+			return map[string]any{"auth": map[string]any{"user": "John"}}, nil
+		},
+	}))
+	e.GET("/", func(c echo.Context) error {
+		Share(c, map[string]any{"app": map[string]any{"name": "app"}})
+
+		shared := Shared(c)
+		shared["auth"].(map[string]any)["user"] = "modified"
+		shared["app"].(map[string]any)["name"] = "modified"
+
+		shared = Shared(c)
+		if v := shared["auth"].(map[string]any)["user"]; v != "John" {
+			t.Errorf("expected the shared data not to be modified, got %v", v)
+		}
+		if v := shared["app"].(map[string]any)["name"]; v != "app" {
+			t.Errorf("expected the shared props not to be modified, got %v", v)
+		}
+		return nil
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,103 @@
+package inertia
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// Shared view data
+// The data of the root template, such as the page title, the locale and the nonce of CSP,
+// can be shared in the same way as the props. It is merged into RenderContext.ViewData.
+
+// ShareView shares the data of the root template.
+// The data is merged into the view data of every page that is rendered as HTML in this request.
+func (i *Inertia) ShareView(data map[string]any) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.sharedViewData == nil {
+		i.sharedViewData = map[string]any{}
+	}
+	for k, v := range data {
+		i.sharedViewData[k] = v
+	}
+}
+
+// SharedView returns the shared view data, including the data of MiddlewareConfig.ShareView.
+// The returned map is a snapshot, so changing it does not affect the shared view data.
+func (i *Inertia) SharedView() map[string]any {
+	data, err := i.resolveSharedViewData()
+	if err != nil {
+		i.echoContext.Logger().Error(err)
+	}
+	return data
+}
+
+// FlushSharedView removes the shared view data, including the data of MiddlewareConfig.ShareView.
+func (i *Inertia) FlushSharedView() {
+	// The lock is not held while resetting, because resetting waits for MiddlewareConfig.ShareView, which may call ShareView.
+	i.shareViewFuncData.reset()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.sharedViewData = nil
+}
+
+// resolveSharedViewData returns the shared view data.
+// MiddlewareConfig.ShareView is called at the first time that the shared view data is needed.
+// It is not called for the Inertia requests, because they do not render the root template.
+func (i *Inertia) resolveSharedViewData() (map[string]any, error) {
+	var data map[string]any
+	if i.shareViewFunc != nil {
+		var err error
+		// The function is called without the lock, because it may call ShareView.
+		data, err = i.shareViewFuncData.get(func() (map[string]any, error) {
+			return i.shareViewFunc(i.echoContext)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return mergeViewData(data, i.sharedViewData), nil
+}
+
+// mergeSharedViewData merges the shared view data into the view data that is passed to RenderWithViewData.
+// The view data of the page takes precedence over the shared view data.
+// The view data that is not a map, such as a struct for a custom renderer, is returned as it is,
+// and the renderer can read the shared view data from RenderContext.SharedViewData.
+func mergeSharedViewData(shared map[string]any, viewData any) any {
+	if len(shared) == 0 {
+		return viewData
+	}
+	switch v := viewData.(type) {
+	case nil:
+		return shared
+	case map[string]any:
+		return mergeViewData(shared, v)
+	}
+	return viewData
+}
+
+func mergeViewData(data ...map[string]any) map[string]any {
+	merged := map[string]any{}
+	for _, d := range data {
+		for k, v := range d {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+func ShareView(c echo.Context, data map[string]any) {
+	MustGet(c).ShareView(data)
+}
+
+func SharedView(c echo.Context) map[string]any {
+	return MustGet(c).SharedView()
+}
+
+func FlushSharedView(c echo.Context) {
+	MustGet(c).FlushSharedView()
+}
@@ -0,0 +1,93 @@
+package inertia
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestShareView(t *testing.T) {
+	tests := []struct {
+		name     string
+		inertia  bool
+		viewData any
+		expected any
+	}{
+		{
+			name:     "shared view data",
+			viewData: nil,
+			expected: map[string]any{"title": "App", "locale": "ja", "nonce": "abc"},
+		},
+		{
+			name:     "view data takes precedence",
+			viewData: map[string]any{"title": "Users"},
+			expected: map[string]any{"title": "Users", "locale": "ja", "nonce": "abc"},
+		},
+		{
+			// The view data for a custom renderer is not changed.
+			name:     "view data is not a map",
+			viewData: struct{ Title string }{Title: "Users"},
+			expected: struct{ Title string }{Title: "Users"},
+		},
+		{
+			name:    "inertia request",
+			inertia: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			var viewData any
+			var sharedViewData map[string]any
+			e := echo.New()
+			e.Use(MiddlewareWithConfig(MiddlewareConfig{
+				Renderer: testNewMockRenderer(t, func(ctx *RenderContext) error {
+					viewData = ctx.ViewData
+					sharedViewData = ctx.SharedViewData
+					return nil
+				}),
+				VersionFunc: func() string { return "" },
+				FlashStore:  NewMemoryFlashStore(),
+				ShareView: func(c echo.Context) (map[string]any, error) {
+					calls++
+					return map[string]any{"title": "App", "locale": "en"}, nil
+				},
+			}))
+			var renderErr error
+			e.GET("/", func(c echo.Context) error {
+				ShareView(c, map[string]any{"locale": "ja", "nonce": "abc"})
+				renderErr = RenderWithViewData(c, "Users", map[string]any{}, tt.viewData)
+				return renderErr
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.inertia {
+				req.Header.Set(HeaderXInertia, "true")
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if tt.inertia {
+				if calls != 0 {
+					t.Errorf("expected ShareView not to be called, got %d calls", calls)
+				}
+				return
+			}
+			if renderErr != nil {
+				t.Fatal(renderErr)
+			}
+			if calls != 1 {
+				t.Errorf("expected ShareView to be called once, got %d calls", calls)
+			}
+			if !testDeepEqual(t, viewData, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, viewData)
+			}
+			if expected := map[string]any{"title": "App", "locale": "ja", "nonce": "abc"}; !testDeepEqual(t, sharedViewData, expected) {
+				t.Errorf("expected shared view data %v, got %v", expected, sharedViewData)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package inertia
 
 import (
+	"net/http"
 	"strings"
 )
 
@@ -28,3 +29,17 @@ func splitAndRemoveEmpty(s string, sep string) []string {
 
 	return ret
 }
+
+// addVary adds the values to the Vary header if they are not in it yet.
+func addVary(h http.Header, values ...string) {
+	var current []string
+	for _, v := range h.Values("Vary") {
+		current = append(current, splitAndRemoveEmpty(v, ",")...)
+	}
+	for _, v := range values {
+		if !inArray(v, current) {
+			h.Add("Vary", v)
+			current = append(current, v)
+		}
+	}
+}
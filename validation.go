@@ -0,0 +1,124 @@
+package inertia
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ValidationErrors is a set of validation error messages keyed by field name.
+// Nested fields are addressed with dot notation like "items.0.name",
+// which is the format that the Inertia.js form helper expects.
+// see https://inertiajs.com/validation
+type ValidationErrors map[string]string
+
+// NewValidationErrors creates ValidationErrors from a nested structure of errors.
+// Nested maps and slices are flattened into dot notation keys,
+// and only the first message is used when a field has multiple messages.
+//
+//	inertia.NewValidationErrors(map[string]any{
+//		"items": []any{
+//			map[string]any{"name": "The name field is required."},
+//		},
+//	})
+//	// => ValidationErrors{"items.0.name": "The name field is required."}
+func NewValidationErrors(errs map[string]any) ValidationErrors {
+	ret := ValidationErrors{}
+	for k, v := range errs {
+		ret.flatten(k, v)
+	}
+	return ret
+}
+
+func (e ValidationErrors) flatten(prefix string, value any) {
+	switch v := value.(type) {
+	case string:
+		e.Add(prefix, v)
+	case []string:
+		if len(v) > 0 {
+			e.Add(prefix, v[0])
+		}
+	case error:
+		e.Add(prefix, v.Error())
+	case map[string]any:
+		for k, vv := range v {
+			e.flatten(prefix+"."+k, vv)
+		}
+	case map[string]string:
+		for k, vv := range v {
+			e.Add(prefix+"."+k, vv)
+		}
+	case []any:
+		// A slice of strings is a list of messages for the field,
+		// otherwise it is a list of nested fields.
+		if len(v) > 0 {
+			if s, ok := v[0].(string); ok {
+				e.Add(prefix, s)
+				return
+			}
+		}
+		for idx, vv := range v {
+			e.flatten(prefix+"."+strconv.Itoa(idx), vv)
+		}
+	}
+}
+
+// Add adds an error message for the field.
+// If the field already has a message, the first one is kept.
+func (e ValidationErrors) Add(key, message string) {
+	if _, exists := e[key]; exists {
+		return
+	}
+	e[key] = message
+}
+
+// Has reports whether the field has an error message.
+func (e ValidationErrors) Has(key string) bool {
+	_, ok := e[key]
+	return ok
+}
+
+// Get returns the error message for the field.
+func (e ValidationErrors) Get(key string) string {
+	return e[key]
+}
+
+// Merge adds all the error messages of other.
+func (e ValidationErrors) Merge(other ValidationErrors) {
+	for k, v := range other {
+		e.Add(k, v)
+	}
+}
+
+// WithErrors attaches validation errors to the response.
+// The errors are sent to the client as the "errors" prop.
+func (i *Inertia) WithErrors(errs ValidationErrors) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.validationErrors == nil {
+		i.validationErrors = ValidationErrors{}
+	}
+	i.validationErrors.Merge(errs)
+}
+
+// resolveValidationErrors returns the value of the "errors" prop.
+// If the request has the X-Inertia-Error-Bag header, the errors are scoped under the bag name.
+// see https://inertiajs.com/validation#error-bags
+// The caller must hold the lock.
+func (i *Inertia) resolveValidationErrors() map[string]any {
+	errs := make(map[string]any, len(i.validationErrors))
+	for k, v := range i.validationErrors {
+		errs[k] = v
+	}
+
+	if len(errs) > 0 && i.errorBag != "" {
+		return map[string]any{
+			i.errorBag: errs,
+		}
+	}
+	return errs
+}
+
+func WithErrors(c echo.Context, errs ValidationErrors) {
+	MustGet(c).WithErrors(errs)
+}
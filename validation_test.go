@@ -0,0 +1,129 @@
+package inertia
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestNewValidationErrors(t *testing.T) {
+	errs := NewValidationErrors(map[string]any{
+		"email":    "The email field is required.",
+		"password": []string{"The password is too short.", "The password is too weak."},
+		"address": map[string]any{
+			"city": errors.New("The city field is required."),
+		},
+		"items": []any{
+			map[string]any{"name": "The name field is required."},
+			map[string]any{"tags": []any{"The tags field is invalid."}},
+		},
+	})
+
+	expected := ValidationErrors{
+		"email":        "The email field is required.",
+		"password":     "The password is too short.",
+		"address.city": "The city field is required.",
+		"items.0.name": "The name field is required.",
+		"items.1.tags": "The tags field is invalid.",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, errs)
+	}
+	for k, v := range expected {
+		if errs.Get(k) != v {
+			t.Errorf("expected %q for %q, got %q", v, k, errs.Get(k))
+		}
+	}
+}
+
+func TestValidationErrors_Add(t *testing.T) {
+	errs := ValidationErrors{}
+	errs.Add("email", "first")
+	errs.Add("email", "second")
+
+	if !errs.Has("email") {
+		t.Fatal("expected email to have an error")
+	}
+	if errs.Get("email") != "first" {
+		t.Errorf("expected the first message to be kept, got %q", errs.Get("email"))
+	}
+	if errs.Has("name") {
+		t.Error("expected name not to have an error")
+	}
+}
+
+func TestWithErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		errorBag string
+		errs     ValidationErrors
+		expected map[string]any
+	}{
+		{
+			name:     "no errors",
+			expected: map[string]any{},
+		},
+		{
+			name: "errors",
+			errs: ValidationErrors{"email": "Invalid email address"},
+			expected: map[string]any{
+				"email": "Invalid email address",
+			},
+		},
+		{
+			name:     "errors with error bag",
+			errorBag: "createUser",
+			errs:     ValidationErrors{"email": "Invalid email address"},
+			expected: map[string]any{
+				"createUser": map[string]any{
+					"email": "Invalid email address",
+				},
+			},
+		},
+		{
+			name:     "no errors with error bag",
+			errorBag: "createUser",
+			expected: map[string]any{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(HeaderXInertia, "true")
+			if tt.errorBag != "" {
+				req.Header.Set(HeaderXInertiaErrorBag, tt.errorBag)
+			}
+			rec := httptest.NewRecorder()
+			e := echo.New()
+			c := e.NewContext(req, rec)
+			m := MiddlewareWithConfig(MiddlewareConfig{
+				Renderer:    testNewMockRenderer(t, func(ctx *RenderContext) error { return nil }),
+				VersionFunc: func() string { return "" },
+				FlashStore:  NewMemoryFlashStore(),
+			})
+
+			err := m(func(c echo.Context) error {
+				if tt.errs != nil {
+					WithErrors(c, tt.errs)
+				}
+				return Render(c, "Users/Create", nil)
+			})(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var page Page
+			if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+				t.Fatal(err)
+			}
+			if !testDeepEqual(t, page.Props["errors"], tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, page.Props["errors"])
+			}
+		})
+	}
+}